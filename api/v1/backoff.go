@@ -0,0 +1,27 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/backoff"
+)
+
+// Backoff decides how long the client waits before retrying a request.
+type Backoff = backoff.Policy
+
+// ConstantBackoff waits the same amount of time before every retry.
+func ConstantBackoff(delay time.Duration) Backoff {
+	return backoff.Constant(delay)
+}
+
+// ExponentialBackoff doubles the delay before every retry, starting
+// at base and never exceeding max. A max of zero means no cap.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return backoff.Exponential(base, max)
+}
+
+// DecorrelatedJitterBackoff picks a random delay between base and three
+// times the previous delay, never exceeding max. A max of zero means no cap.
+func DecorrelatedJitterBackoff(base, max time.Duration) Backoff {
+	return backoff.DecorrelatedJitter(base, max)
+}
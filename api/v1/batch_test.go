@@ -0,0 +1,179 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// Serves batch requests. A batch that contains the path `fail` is rejected
+// as a whole, and every other query's result is its path.
+func newBatchDataPlane(t *testing.T) *dataPlane {
+	d := newDataPlane(t)
+	d.handle(dataPlaneBatchPath, func(w http.ResponseWriter, r *http.Request) {
+		request := &struct {
+			Items []struct {
+				Path string `json:"path"`
+			} `json:"items"`
+		}{}
+
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			t.Errorf("decoding batch request: %v", err)
+		}
+
+		results := make([]map[string]interface{}, 0, len(request.Items))
+		for _, item := range request.Items {
+			if item.Path == "fail" {
+				respond(w, http.StatusBadRequest, map[string]string{
+					"code":    "invalid_parameter",
+					"message": "bad batch",
+				})
+
+				return
+			}
+
+			results = append(results, map[string]interface{}{
+				"result": item.Path,
+			})
+		}
+
+		respond(w, http.StatusOK, map[string]interface{}{
+			"result": results,
+		})
+	})
+
+	return d
+}
+
+func newBatchQueries(paths ...string) []Query {
+	queries := make([]Query, 0, len(paths))
+	for _, path := range paths {
+		queries = append(queries, Query{
+			Path: path,
+		})
+	}
+
+	return queries
+}
+
+func TestBatchQueryPartialResults(t *testing.T) {
+	client := newBatchDataPlane(t).client(t, &Settings{
+		BatchLimit:          2,
+		BatchConcurrency:    2,
+		BatchPartialResults: true,
+	})
+	queries := newBatchQueries("a", "b", "fail", "c", "d")
+
+	err := client.BatchQuery(context.Background(), queries, nil)
+
+	var batchError *BatchError
+	if !errors.As(err, &batchError) {
+		t.Fatalf("expected a batch error, got %v", err)
+	}
+
+	if expected := []int{2, 3}; !reflect.DeepEqual(batchError.Indices, expected) {
+		t.Errorf("expected failed indices %v, got %v", expected, batchError.Indices)
+	}
+
+	if batchError.Total != len(queries) {
+		t.Errorf("expected a total of %d, got %d", len(queries), batchError.Total)
+	}
+
+	for _, i := range []int{0, 1, 4} {
+		if queries[i].Error != nil || queries[i].Result != queries[i].Path {
+			t.Errorf("expected query %d to succeed, got result %v and error %v", i, queries[i].Result, queries[i].Error)
+		}
+	}
+
+	for _, i := range batchError.Indices {
+		if queries[i].Error == nil || queries[i].Result != nil {
+			t.Errorf("expected query %d to fail, got result %v and error %v", i, queries[i].Result, queries[i].Error)
+		}
+	}
+}
+
+func TestBatchQueryAllOrNothing(t *testing.T) {
+	client := newBatchDataPlane(t).client(t, &Settings{
+		BatchLimit: 2,
+	})
+	queries := newBatchQueries("a", "b", "fail", "c", "d")
+
+	err := client.BatchQuery(context.Background(), queries, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var batchError *BatchError
+	if errors.As(err, &batchError) {
+		t.Fatalf("expected a plain error, got %v", err)
+	}
+
+	for i, query := range queries {
+		if query.Result != nil || query.Error != nil {
+			t.Errorf("expected query %d to be untouched, got result %v and error %v", i, query.Result, query.Error)
+		}
+	}
+}
+
+func TestBatchQueryRetriesChunksIndependently(t *testing.T) {
+	var flaky, batches int32
+
+	d := newDataPlane(t)
+	d.handle(dataPlaneBatchPath, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&batches, 1)
+
+		request := &struct {
+			Items []struct {
+				Path string `json:"path"`
+			} `json:"items"`
+		}{}
+
+		json.NewDecoder(r.Body).Decode(request)
+
+		results := make([]map[string]interface{}, 0, len(request.Items))
+		for _, item := range request.Items {
+			if item.Path == "flaky" && atomic.AddInt32(&flaky, 1) == 1 {
+				respond(w, http.StatusServiceUnavailable, map[string]string{
+					"code":    "unavailable",
+					"message": "try again",
+				})
+
+				return
+			}
+
+			results = append(results, map[string]interface{}{
+				"result": item.Path,
+			})
+		}
+
+		respond(w, http.StatusOK, map[string]interface{}{
+			"result": results,
+		})
+	})
+
+	client := d.client(t, &Settings{
+		MaxRetries: 3,
+		BatchLimit: 2,
+	})
+
+	queries := newBatchQueries("a", "b", "flaky", "c", "d")
+
+	if err := client.BatchQuery(context.Background(), queries, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, query := range queries {
+		if query.Result != query.Path {
+			t.Errorf("expected query %d to have result %s, got %v", i, query.Path, query.Result)
+		}
+	}
+
+	// Three chunks, and only the flaky one is sent twice.
+	if n := atomic.LoadInt32(&batches); n != 4 {
+		t.Errorf("expected 4 batch requests, got %d", n)
+	}
+}
@@ -0,0 +1,112 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/cache"
+)
+
+type CacheSettings struct {
+	// How long a decision is cached. Defaults to 10 seconds.
+	TTL time.Duration
+
+	// The maximum number of cached decisions. The least recently
+	// used decision is evicted when it's exceeded. Defaults to 10000.
+	MaxEntries int
+
+	// Whether undefined, null and false results are cached as well.
+	Negative bool
+
+	// Optional callback that, given the path of a PutData or DeleteData
+	// call, emits the query paths whose decisions are affected. Each
+	// path also covers the paths nested under it. If nil, every cached
+	// decision is invalidated when data is written.
+	Affected func(path string) []string
+}
+
+var (
+	negativeResults = [][]byte{
+		[]byte("null"),
+		[]byte("false"),
+	}
+)
+
+func newCache(settings *CacheSettings) cache.Cache {
+	if settings == nil {
+		return nil
+	}
+
+	return cache.New(
+		&cache.Settings{
+			TTL:        settings.TTL,
+			MaxEntries: settings.MaxEntries,
+		},
+	)
+}
+
+// Decisions are keyed by path and canonical input. Maps are marshalled
+// with sorted keys, so equal inputs produce equal keys.
+func cacheKey(path string, input interface{}) (string, bool) {
+	bytes, err := json.Marshal(input)
+	if err != nil {
+		return "", false
+	}
+
+	return path + "\x00" + string(bytes), true
+}
+
+func (c *client) cacheable(result json.RawMessage) bool {
+	if c.settings.Cache.Negative {
+		return true
+	}
+
+	if len(result) == 0 {
+		return false
+	}
+
+	for _, negative := range negativeResults {
+		if bytes.Equal(result, negative) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Invalidate removes cached decisions for the given query path and
+// any paths nested under it. It's a no-op if caching is disabled.
+func (c *client) Invalidate(path string) {
+	atomic.AddUint64(&c.generation, 1)
+
+	if c.cache != nil {
+		c.cache.Invalidate(path)
+	}
+}
+
+// InvalidateAll removes every cached decision.
+func (c *client) InvalidateAll() {
+	atomic.AddUint64(&c.generation, 1)
+
+	if c.cache != nil {
+		c.cache.InvalidateAll()
+	}
+}
+
+func (c *client) invalidateData(path string) {
+	atomic.AddUint64(&c.generation, 1)
+
+	if c.cache == nil {
+		return
+	}
+
+	if affected := c.settings.Cache.Affected; affected != nil {
+		for _, path := range affected(path) {
+			c.cache.Invalidate(path)
+		}
+	} else {
+		c.cache.InvalidateAll()
+	}
+}
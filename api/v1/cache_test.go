@@ -0,0 +1,153 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+// Serves `allow` as true and counts how often it was queried.
+func newCountingDataPlane(t *testing.T, block chan struct{}) (*dataPlane, *int32) {
+	var queries int32
+
+	d := newDataPlane(t)
+	d.handle("/data/allow", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&queries, 1) == 1 && block != nil {
+			block <- struct{}{}
+			<-block
+		}
+
+		respond(w, http.StatusOK, map[string]interface{}{
+			"result": true,
+			"metrics": map[string]interface{}{
+				"timer_rego_query_eval_ns": 1,
+			},
+		})
+	})
+	d.handle("/data/users", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, map[string]interface{}{})
+	})
+
+	return d, &queries
+}
+
+func check(t *testing.T, client Client) {
+	t.Helper()
+
+	if allowed, err := client.Check(context.Background(), "allow", nil); err != nil || !allowed {
+		t.Fatalf("expected to be allowed, got %v and %v", allowed, err)
+	}
+}
+
+func TestCacheInvalidatedByWrite(t *testing.T) {
+	d, queries := newCountingDataPlane(t, nil)
+
+	client := d.client(t, &Settings{
+		Cache: &CacheSettings{},
+	})
+
+	check(t, client)
+	check(t, client)
+
+	if n := atomic.LoadInt32(queries); n != 1 {
+		t.Fatalf("expected the second check to be cached, got %d queries", n)
+	}
+
+	if err := client.PutData(context.Background(), "users", map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, client)
+
+	if n := atomic.LoadInt32(queries); n != 2 {
+		t.Errorf("expected the write to invalidate the cache, got %d queries", n)
+	}
+}
+
+func TestCacheAffectedPaths(t *testing.T) {
+	d, queries := newCountingDataPlane(t, nil)
+
+	client := d.client(t, &Settings{
+		Cache: &CacheSettings{
+			Affected: func(path string) []string {
+				return []string{"rbac"}
+			},
+		},
+	})
+
+	check(t, client)
+
+	if err := client.PutData(context.Background(), "users", map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, client)
+
+	if n := atomic.LoadInt32(queries); n != 1 {
+		t.Errorf("expected unaffected decisions to stay cached, got %d queries", n)
+	}
+
+	client.Invalidate("allow")
+	check(t, client)
+
+	if n := atomic.LoadInt32(queries); n != 2 {
+		t.Errorf("expected Invalidate to remove the decision, got %d queries", n)
+	}
+}
+
+func TestCacheSkipsDecisionsFetchedBeforeWrite(t *testing.T) {
+	block := make(chan struct{})
+	d, queries := newCountingDataPlane(t, block)
+
+	client := d.client(t, &Settings{
+		Cache: &CacheSettings{},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		if allowed, err := client.Check(context.Background(), "allow", nil); err != nil || !allowed {
+			t.Errorf("expected to be allowed, got %v and %v", allowed, err)
+		}
+	}()
+
+	// Write while the first query is in flight.
+	<-block
+
+	if err := client.PutData(context.Background(), "users", map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	block <- struct{}{}
+	<-done
+
+	check(t, client)
+
+	if n := atomic.LoadInt32(queries); n != 2 {
+		t.Errorf("expected the decision fetched before the write not to be cached, got %d queries", n)
+	}
+}
+
+func TestCacheCopiesMetadata(t *testing.T) {
+	d, _ := newCountingDataPlane(t, nil)
+
+	client := d.client(t, &Settings{
+		Cache: &CacheSettings{},
+	})
+
+	var result bool
+	for i := 0; i < 2; i++ {
+		response, err := client.QueryWithResponse(context.Background(), "allow", nil, &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if value := response.Metrics["timer_rego_query_eval_ns"]; value != float64(1) {
+			t.Fatalf("expected the cached metrics to be unchanged, got %v", value)
+		}
+
+		response.Metrics["timer_rego_query_eval_ns"] = float64(2)
+	}
+}
@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestStrictCheck(t *testing.T) {
+	d := newDataPlane(t)
+	d.handle("/data/undefined", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, map[string]interface{}{})
+	})
+	d.handle("/data/object", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, map[string]interface{}{
+			"result": map[string]interface{}{
+				"allow": true,
+			},
+		})
+	})
+
+	lenient := d.client(t, &Settings{})
+	strict := d.client(t, &Settings{
+		StrictCheck: true,
+	})
+	pointer := d.client(t, &Settings{
+		StrictCheck:  true,
+		CheckPointer: "/allow",
+	})
+
+	for _, path := range []string{"undefined", "object"} {
+		if allowed, err := lenient.Check(context.Background(), path, nil); allowed || err != nil {
+			t.Errorf("%s: expected a denial, got %v and %v", path, allowed, err)
+		}
+	}
+
+	var undefined *UndefinedResultError
+	if _, err := strict.Check(context.Background(), "undefined", nil); !errors.As(err, &undefined) || undefined.Path != "undefined" {
+		t.Errorf("expected an undefined result error, got %v", err)
+	}
+
+	var nonBoolean *NonBooleanResultError
+	if _, err := strict.Check(context.Background(), "object", nil); !errors.As(err, &nonBoolean) {
+		t.Errorf("expected a non boolean result error, got %v", err)
+	}
+
+	if allowed, err := pointer.Check(context.Background(), "object", nil); !allowed || err != nil {
+		t.Errorf("expected the pointer to select true, got %v and %v", allowed, err)
+	}
+}
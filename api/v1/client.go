@@ -2,11 +2,21 @@ package v1
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/styrainc/styra-run-sdk-go/internal/auth"
+	"github.com/styrainc/styra-run-sdk-go/internal/cache"
 	"github.com/styrainc/styra-run-sdk-go/internal/discovery"
 	"github.com/styrainc/styra-run-sdk-go/internal/errors"
+	"github.com/styrainc/styra-run-sdk-go/internal/flight"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/rest"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 )
@@ -15,18 +25,26 @@ const (
 	dataPlanePath      = "/data"
 	dataPlaneBatchPath = "/data_batch"
 	batchLimit         = 20
+	batchConcurrency   = 4
+
+	// The data plane only sends metrics and provenance when asked to.
+	decisionMetadata = "?metrics=true&provenance=true"
 )
 
 type DiscoveryStrategy uint
 
 const (
 	Simple DiscoveryStrategy = iota
+	Sorted
+	Affinity
 )
 
 var (
 	// Do this to simplify sdk initialization and avoid referencing other packages.
 	discoveryStrategyToStrategyType = map[DiscoveryStrategy]discovery.StrategyType{
-		Simple: discovery.Simple,
+		Simple:   discovery.Simple,
+		Sorted:   discovery.Sorted,
+		Affinity: discovery.Affinity,
 	}
 )
 
@@ -42,7 +60,102 @@ type Settings struct {
 	Url               string
 	DiscoveryStrategy DiscoveryStrategy
 	MaxRetries        int
+	ProbeInterval     time.Duration
+	Region            string
+	Zone              string
 	Client            *http.Client
+
+	// Optional source of tokens, consulted for every request so that tokens
+	// can be rotated without restarting. Defaults to the static Token.
+	TokenSource TokenSource
+
+	// How often the list of data plane urls is re-discovered. Disabled if zero.
+	RefreshInterval time.Duration
+
+	// How many consecutive requests must fail on every url before the
+	// list of data plane urls is re-discovered. Defaults to 3.
+	RefreshAfterFailures int
+
+	// How long to wait between retries. Retries are immediate if nil.
+	Backoff Backoff
+
+	// The maximum time spent retrying a single request. Unbounded if zero.
+	MaxElapsedTime time.Duration
+
+	// How long a failed discovery of data plane urls is remembered before
+	// it's attempted again. Until then, requests fail fast with the same
+	// error. Defaults to exponential backoff from 1 to 30 seconds.
+	DiscoveryBackoff Backoff
+
+	// Optional data plane urls used if the initial discovery fails and
+	// there's no list persisted in GatewaysFile. Discovery is retried
+	// in the background until it succeeds.
+	FallbackGateways []string
+
+	// Optional file the discovered data plane urls are persisted to. If the
+	// initial discovery fails, e.g. during a control plane outage, the list
+	// is reloaded from it. Preferred over FallbackGateways.
+	GatewaysFile string
+
+	// Decides which errors are retried on the next data plane url. Defaults
+	// to DefaultRetryable(RetryTooManyRequests).
+	Retryable func(err error) bool
+
+	// Whether 429 too many requests responses are retried by default.
+	RetryTooManyRequests bool
+
+	// Optional per url circuit breaker. Disabled if nil.
+	CircuitBreaker *CircuitBreakerSettings
+
+	// Optional hedging of GetData, Query, Check and BatchQuery. Disabled if nil.
+	Hedging *HedgingSettings
+
+	// Optional caching of Query and Check decisions. Disabled if nil.
+	Cache *CacheSettings
+
+	// Whether identical concurrent Query and Check calls, i.e. calls with the
+	// same path and input, share a single request to the data plane.
+	Coalesce bool
+
+	// The maximum number of queries sent to the data plane in a single
+	// batch request. Defaults to 20, which is the data plane limit, and
+	// larger values are lowered to it.
+	BatchLimit int
+
+	// How many batch requests for the same BatchQuery call are in flight
+	// at once. Defaults to 4, set it to 1 to send batches one after the
+	// other.
+	BatchConcurrency int
+
+	// By default, BatchQuery either fills in every query or none of them.
+	// If set, the queries of a batch that failed get their Error field set
+	// instead, and a *BatchError listing their indices is returned.
+	BatchPartialResults bool
+
+	// By default, Check returns false for any result other than true. If
+	// set, Check returns an *UndefinedResultError for undefined results and
+	// a *NonBooleanResultError for results that aren't booleans instead.
+	StrictCheck bool
+
+	// Optional json pointer, e.g. `/allow`, that selects the boolean
+	// within the result document that Check looks at.
+	CheckPointer string
+
+	// Optional interceptors that every outgoing request passes through,
+	// including discovery requests. The first one is the outermost one.
+	Interceptors []Interceptor
+
+	// Optional observers that are notified of retries, failovers and
+	// discovery of data plane urls.
+	Observers []*Observer
+
+	// Optional logger for retries, url switches, discovery failures and
+	// decoding errors. Tokens are never logged.
+	Logger *slog.Logger
+
+	// Whether inputs are included in log records. They're redacted by
+	// default since they may contain personal information.
+	LogInputs bool
 }
 
 type Client interface {
@@ -50,86 +163,165 @@ type Client interface {
 	PutData(ctx context.Context, path string, data interface{}) error
 	DeleteData(ctx context.Context, path string) error
 	Query(ctx context.Context, path string, input, result interface{}) error
+	QueryWithResponse(ctx context.Context, path string, input, result interface{}) (*QueryResponse, error)
 	Check(ctx context.Context, path string, input interface{}) (bool, error)
 	BatchQuery(ctx context.Context, queries []Query, input interface{}) error
+	Invalidate(path string)
+	InvalidateAll()
+	Warmup(ctx context.Context) error
+	Start(ctx context.Context) error
+	Ready() error
+	Close()
 }
 
 type client struct {
 	settings *Settings
 	executor discovery.Executor
+	cache    cache.Cache
+	flight   flight.Group
+	logger   *slog.Logger
+
+	// Counts writes and invalidations, so that decisions fetched before
+	// one of them aren't shared afterwards. The cache keeps its own count.
+	generation uint64
 }
 
 func New(settings *Settings) Client {
+	if settings.BatchLimit <= 0 || settings.BatchLimit > batchLimit {
+		settings.BatchLimit = batchLimit
+	}
+
+	if settings.BatchConcurrency <= 0 {
+		settings.BatchConcurrency = batchConcurrency
+	}
+
+	if settings.TokenSource == nil {
+		settings.TokenSource = StaticToken(settings.Token)
+	}
+
 	return &client{
 		settings: settings,
+		cache:    newCache(settings.Cache),
+		logger:   logging.OrDiscard(settings.Logger),
 		executor: discovery.NewExecutor(
 			&discovery.ExecutorSettings{
-				Token:        settings.Token,
-				Url:          settings.Url,
-				StrategyType: discoveryStrategyToStrategyType[settings.DiscoveryStrategy],
-				MaxRetries:   settings.MaxRetries,
-				Client:       settings.Client,
+				TokenSource:   settings.TokenSource,
+				Url:           settings.Url,
+				StrategyType:  discoveryStrategyToStrategyType[settings.DiscoveryStrategy],
+				MaxRetries:    settings.MaxRetries,
+				ProbeInterval: settings.ProbeInterval,
+				Region:        settings.Region,
+				Zone:          settings.Zone,
+				Client:        settings.Client,
+
+				RefreshInterval:      settings.RefreshInterval,
+				RefreshAfterFailures: settings.RefreshAfterFailures,
+				Backoff:              settings.Backoff,
+				MaxElapsedTime:       settings.MaxElapsedTime,
+				InitBackoff:          settings.DiscoveryBackoff,
+				FallbackGateways:     settings.FallbackGateways,
+				GatewaysFile:         settings.GatewaysFile,
+				Retryable:            retryable(settings),
+				Breaker:              settings.CircuitBreaker,
+				Hedge:                settings.Hedging,
+				Interceptors:         settings.Interceptors,
+				Observers:            settings.Observers,
+				Logger:               settings.Logger,
 			},
 		),
 	}
 }
 
 func (c *client) GetData(ctx context.Context, path string, data interface{}) error {
-	return c.executor.Try(
+	value, err := c.executor.Hedge(
 		ctx,
-		func(url string) error {
-			return c.getData(ctx, url, path, data)
+		func(ctx context.Context, url string) (interface{}, error) {
+			return c.getData(ctx, url, path)
 		},
 	)
+	if err != nil {
+		return err
+	}
+
+	return c.decode(ctx, path, nil, value.(json.RawMessage), data)
 }
 
-func (c *client) getData(ctx context.Context, url, path string, result interface{}) error {
+func (c *client) getData(ctx context.Context, url, path string) (json.RawMessage, error) {
 	response := &struct {
-		Result interface{} `json:"result"`
-	}{
-		Result: result,
-	}
+		Result json.RawMessage `json:"result"`
+	}{}
+
+	gateway := url
 
 	url, err := utils.JoinPath(url, dataPlanePath, path)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	headers, err := c.headers(ctx, false)
+	if err != nil {
+		return nil, err
 	}
 
 	rest := &rest.Rest{
 		Url:     url,
 		Method:  http.MethodGet,
 		Client:  c.settings.Client,
-		Headers: c.bearer(),
+		Headers: headers,
 		Decoder: errors.HttpErrorDecoder(response),
+
+		Operation:    OperationGetData,
+		Gateway:      gateway,
+		Path:         path,
+		Interceptors: c.settings.Interceptors,
 	}
 	if err := rest.Execute(ctx); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return response.Result, nil
 }
 
 func (c *client) PutData(ctx context.Context, path string, data interface{}) error {
-	return c.executor.Try(
+	if err := c.executor.Try(
 		ctx,
-		func(url string) error {
+		func(ctx context.Context, url string) error {
 			return c.putData(ctx, url, path, data)
 		},
-	)
+	); err != nil {
+		return err
+	}
+
+	c.invalidateData(path)
+
+	return nil
 }
 
 func (c *client) putData(ctx context.Context, url, path string, data interface{}) error {
+	gateway := url
+
 	url, err := utils.JoinPath(url, dataPlanePath, path)
 	if err != nil {
 		return err
 	}
 
+	headers, err := c.headers(ctx, true)
+	if err != nil {
+		return err
+	}
+
 	rest := &rest.Rest{
 		Url:     url,
 		Method:  http.MethodPut,
 		Client:  c.settings.Client,
-		Headers: c.bearerAndJson(),
+		Headers: headers,
 		Encoder: rest.JsonEncoder(data),
+
+		Operation:    OperationPutData,
+		Gateway:      gateway,
+		Path:         path,
+		Input:        data,
+		Interceptors: c.settings.Interceptors,
 	}
 
 	if err := rest.Execute(ctx); err != nil {
@@ -140,25 +332,43 @@ func (c *client) putData(ctx context.Context, url, path string, data interface{}
 }
 
 func (c *client) DeleteData(ctx context.Context, path string) error {
-	return c.executor.Try(
+	if err := c.executor.Try(
 		ctx,
-		func(url string) error {
+		func(ctx context.Context, url string) error {
 			return c.deleteData(ctx, url, path)
 		},
-	)
+	); err != nil {
+		return err
+	}
+
+	c.invalidateData(path)
+
+	return nil
 }
 
 func (c *client) deleteData(ctx context.Context, url, path string) error {
+	gateway := url
+
 	url, err := utils.JoinPath(url, dataPlanePath, path)
 	if err != nil {
 		return err
 	}
 
+	headers, err := c.headers(ctx, false)
+	if err != nil {
+		return err
+	}
+
 	rest := &rest.Rest{
 		Url:     url,
 		Method:  http.MethodDelete,
 		Client:  c.settings.Client,
-		Headers: c.bearer(),
+		Headers: headers,
+
+		Operation:    OperationDeleteData,
+		Gateway:      gateway,
+		Path:         path,
+		Interceptors: c.settings.Interceptors,
 	}
 	if err := rest.Execute(ctx); err != nil {
 		return err
@@ -168,92 +378,269 @@ func (c *client) deleteData(ctx context.Context, url, path string) error {
 }
 
 func (c *client) Query(ctx context.Context, path string, input, result interface{}) error {
-	return c.executor.Try(
-		ctx,
-		func(url string) error {
-			return c.query(ctx, url, path, input, result)
-		},
-	)
+	decision, err := c.evaluate(ctx, path, input)
+	if err != nil {
+		return err
+	}
+
+	return c.decode(ctx, path, input, decision.Result, result)
+}
+
+func (c *client) QueryWithResponse(ctx context.Context, path string, input, result interface{}) (*QueryResponse, error) {
+	decision, err := c.evaluate(ctx, path, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.decode(ctx, path, input, decision.Result, result); err != nil {
+		return nil, err
+	}
+
+	return decision.response(), nil
+}
+
+// Evaluate a query, consulting the decision cache and collapsing
+// identical concurrent queries if either is enabled.
+func (c *client) evaluate(ctx context.Context, path string, input interface{}) (*decision, error) {
+	key, ok := "", false
+	if c.cache != nil || c.settings.Coalesce {
+		key, ok = cacheKey(path, input)
+	}
+
+	if ok && c.cache != nil {
+		if value, hit := c.cache.Get(key); hit {
+			return value.(*decision).fromCache(), nil
+		}
+	}
+
+	generation := atomic.LoadUint64(&c.generation)
+
+	var cached uint64
+	if c.cache != nil {
+		cached = c.cache.Generation()
+	}
+
+	fetch := func(ctx context.Context) (interface{}, error) {
+		return c.executor.Hedge(
+			ctx,
+			func(ctx context.Context, url string) (interface{}, error) {
+				return c.query(ctx, url, path, input)
+			},
+		)
+	}
+
+	var value interface{}
+	var err error
+	if ok && c.settings.Coalesce {
+		// Callers that arrive after a write must not join a flight that
+		// started before it, so flights are keyed by generation as well.
+		value, err = c.flight.Do(ctx, fmt.Sprintf("%s\x00%d", key, generation), fetch)
+	} else {
+		value, err = fetch(ctx)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := value.(*decision)
+
+	// If data was written while the decision was in flight, it may
+	// already be stale, so the cache only hands it to this caller.
+	if ok && c.cache != nil && c.cacheable(result.Result) {
+		c.cache.Put(key, path, result, cached)
+	}
+
+	return result, nil
 }
 
-func (c *client) query(ctx context.Context, url, path string, input, result interface{}) error {
+func (c *client) query(ctx context.Context, url, path string, input interface{}) (*decision, error) {
 	request := &struct {
 		Input interface{} `json:"input"`
 	}{
 		Input: input,
 	}
 
-	response := &struct {
-		Result interface{} `json:"result"`
-	}{
-		Result: result,
-	}
+	response := &decision{}
+
+	gateway := url
 
 	url, err := utils.JoinPath(url, dataPlanePath, path)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	headers, err := c.headers(ctx, true)
+	if err != nil {
+		return nil, err
 	}
 
 	rest := &rest.Rest{
-		Url:     url,
+		Url:     url + decisionMetadata,
 		Method:  http.MethodPost,
 		Client:  c.settings.Client,
-		Headers: c.bearerAndJson(),
+		Headers: headers,
 		Encoder: rest.JsonEncoder(request),
 		Decoder: errors.HttpErrorDecoder(response),
+
+		Operation:    OperationQuery,
+		Gateway:      gateway,
+		Path:         path,
+		Input:        input,
+		Interceptors: c.settings.Interceptors,
 	}
 
+	start := time.Now()
 	if err := rest.Execute(ctx); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	response.gateway = gateway
+	response.duration = time.Since(start)
+
+	return response, nil
 }
 
 func (c *client) Check(ctx context.Context, path string, input interface{}) (bool, error) {
-	var result interface{}
-
-	if err := c.Query(ctx, path, input, &result); err != nil {
+	decision, err := c.evaluate(ctx, path, input)
+	if err != nil {
 		return false, err
 	}
 
-	if value, ok := result.(bool); ok && value {
-		return true, nil
-	} else {
-		return false, nil
+	raw := decision.Result
+
+	var result interface{}
+	if err := c.decode(ctx, path, input, raw, &result); err != nil {
+		return false, err
 	}
+
+	return utils.CheckResult(path, result, len(raw) > 0, c.settings.CheckPointer, c.settings.StrictCheck)
 }
 
 func (c *client) BatchQuery(ctx context.Context, queries []Query, input interface{}) error {
-	return c.executor.Try(
-		ctx,
-		func(url string) error {
-			return c.batchQuery(ctx, url, queries, input)
-		},
-	)
+	items, err := c.batchQuery(ctx, queries, input)
+
+	// Results are only written back if every batch succeeded, unless
+	// partial results were requested, in which case every query in a
+	// failed batch has its error set instead.
+	if err != nil {
+		if _, ok := err.(*BatchError); !ok {
+			return err
+		}
+	}
+
+	for i, item := range items {
+		queries[i].Result = item.Result
+		queries[i].Error = item.Error
+	}
+
+	return err
+}
+
+type batchItem struct {
+	Result interface{}           `json:"result"`
+	Error  *errors.ErrorResponse `json:"error"`
 }
 
-func (c *client) batchQuery(ctx context.Context, url string, queries []Query, input interface{}) error {
+// Every batch goes through the executor on its own, so a failed batch is
+// retried without resending the ones that already succeeded. Batches are
+// sent concurrently up to the configured limit. If one fails, the ones
+// still in flight are cancelled, unless partial results were requested.
+func (c *client) batchQuery(ctx context.Context, queries []Query, input interface{}) ([]*batchItem, error) {
 	size := len(queries)
+	limit := c.settings.BatchLimit
+	partial := c.settings.BatchPartialResults
+	result := make([]*batchItem, size)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var failure error
+	var failed []int
+
+	semaphore := make(chan struct{}, c.settings.BatchConcurrency)
 
-	for i := 0; i < size; i += batchLimit {
+	for i := 0; i < size; i += limit {
 		var batch []Query
 
-		if i+batchLimit < size {
-			batch = queries[i : i+batchLimit]
+		if i+limit < size {
+			batch = queries[i : i+limit]
 		} else {
 			batch = queries[i:size]
 		}
 
-		if err := c.doBatchQuery(ctx, url, batch, input); err != nil {
-			return err
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
 		}
+
+		wg.Add(1)
+
+		go func(offset int, batch []Query) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			value, err := c.executor.Hedge(
+				ctx,
+				func(ctx context.Context, url string) (interface{}, error) {
+					return c.doBatchQuery(ctx, url, batch, input)
+				},
+			)
+
+			if err == nil {
+				copy(result[offset:], value.([]*batchItem))
+				return
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if failure == nil {
+				failure = err
+			}
+
+			if !partial {
+				cancel()
+				return
+			}
+
+			details := errorResponse(err)
+			for i := range batch {
+				result[offset+i] = &batchItem{
+					Error: details,
+				}
+
+				failed = append(failed, offset+i)
+			}
+		}(i, batch)
 	}
 
-	return nil
+	wg.Wait()
+
+	if failure != nil && !partial {
+		return nil, failure
+	} else if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if failure != nil {
+		sort.Ints(failed)
+
+		return result, &BatchError{
+			Indices: failed,
+			Total:   size,
+			Err:     failure,
+		}
+	}
+
+	return result, nil
 }
 
-func (c *client) doBatchQuery(ctx context.Context, url string, queries []Query, input interface{}) error {
+func (c *client) doBatchQuery(ctx context.Context, url string, queries []Query, input interface{}) ([]*batchItem, error) {
 	type item struct {
 		Path  string      `json:"path"`
 		Input interface{} `json:"input"`
@@ -278,47 +665,133 @@ func (c *client) doBatchQuery(ctx context.Context, url string, queries []Query,
 	}
 
 	response := &struct {
-		Result []struct {
-			Result interface{}           `json:"result"`
-			Error  *errors.ErrorResponse `json:"error"`
-		} `json:"result"`
+		Result []*batchItem `json:"result"`
 	}{}
 
+	gateway := url
+
 	url, err := utils.JoinPath(url, dataPlaneBatchPath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	headers, err := c.headers(ctx, true)
+	if err != nil {
+		return nil, err
 	}
 
 	rest := &rest.Rest{
 		Url:     url,
 		Method:  http.MethodPost,
 		Client:  c.settings.Client,
-		Headers: c.bearerAndJson(),
+		Headers: headers,
 		Encoder: rest.JsonEncoder(request),
 		Decoder: errors.HttpErrorDecoder(response),
+
+		Operation:    OperationBatchQuery,
+		Gateway:      gateway,
+		Input:        request,
+		Interceptors: c.settings.Interceptors,
 	}
 
 	if err := rest.Execute(ctx); err != nil {
-		return err
+		return nil, err
 	}
 
-	for i, item := range response.Result {
-		queries[i].Result = item.Result
-		queries[i].Error = item.Error
+	// Pad the response in case the data plane emits fewer items than requested.
+	result := make([]*batchItem, len(queries))
+	for i := range result {
+		if i < len(response.Result) && response.Result[i] != nil {
+			result[i] = response.Result[i]
+		} else {
+			result[i] = &batchItem{}
+		}
 	}
 
-	return nil
+	return result, nil
+}
+
+// Decode a raw result into the caller's value, logging failures,
+// which usually mean the value doesn't match the policy output.
+func (c *client) decode(ctx context.Context, path string, input interface{}, raw json.RawMessage, value interface{}) error {
+	err := decode(raw, value)
+	if err != nil {
+		c.logger.WarnContext(
+			ctx,
+			"styra run result could not be decoded",
+			"path", path,
+			logging.Sensitive("input", input, c.settings.LogInputs),
+			"error", err,
+		)
+	}
+
+	return err
+}
+
+// Decode a raw result into the caller's value. A missing
+// result leaves the value untouched.
+func decode(raw json.RawMessage, value interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(raw, value)
+}
+
+// Gateway returns the data plane url currently in use, or an empty
+// string if data plane urls haven't been discovered yet.
+func (c *client) Gateway() string {
+	return c.executor.Gateway()
+}
+
+// Warmup discovers the data plane urls unless that already happened, so
+// that services can fail fast at startup. Concurrent calls and requests
+// share a single discovery. A previous failure doesn't prevent a retry.
+func (c *client) Warmup(ctx context.Context) error {
+	return c.executor.Init(ctx)
 }
 
-func (c *client) bearer() map[string]string {
-	return map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", c.settings.Token),
+// Start discovers the data plane urls, like Warmup, and then reports
+// whether the client is ready.
+func (c *client) Start(ctx context.Context) error {
+	if err := c.executor.Init(ctx); err != nil {
+		return err
 	}
+
+	return c.Ready()
+}
+
+// Ready returns nil if the data plane urls were discovered and one of them
+// is reachable, i.e. the most recent request didn't fail on every url it
+// tried. It doesn't make any requests, so it's cheap enough for readiness
+// probes.
+func (c *client) Ready() error {
+	return c.executor.Ready()
+}
+
+// Close rejects new requests with ClientClosedError, waits for requests
+// in flight to finish and stops background work such as refreshes and
+// probes. Cached decisions are still served after Close.
+func (c *client) Close() {
+	c.executor.Close()
 }
 
-func (c *client) bearerAndJson() map[string]string {
-	return map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", c.settings.Token),
-		"Content-Type":  utils.ApplicationJson,
+func (c *client) headers(ctx context.Context, json bool) (map[string]string, error) {
+	token, ok := auth.FromContext(ctx)
+	if !ok {
+		var err error
+		if token, err = c.settings.TokenSource.Token(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	headers := map[string]string{
+		"Authorization": auth.Bearer(token),
 	}
+
+	if json {
+		headers["Content-Type"] = utils.ApplicationJson
+	}
+
+	return headers, nil
 }
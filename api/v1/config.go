@@ -0,0 +1,412 @@
+package v1
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/rest"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix = "STYRA_RUN_"
+)
+
+var (
+	missingValueError = errors.New("missing value")
+	invalidValueError = errors.New("invalid value")
+
+	discoveryStrategies = map[string]DiscoveryStrategy{
+		"simple":   Simple,
+		"sorted":   Sorted,
+		"affinity": Affinity,
+	}
+)
+
+// ConfigError is returned by SettingsFromEnv and SettingsFromFile for a
+// missing or malformed value. Key is the environment variable or the file
+// key, e.g. `STYRA_RUN_URL` or `tls.ca_file`.
+type ConfigError struct {
+	Key string
+	Err error
+}
+
+func (c *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", c.Key, c.Err)
+}
+
+func (c *ConfigError) Unwrap() error {
+	return c.Err
+}
+
+type tlsConfig struct {
+	CaFile             string `json:"ca_file" yaml:"ca_file"`
+	CertFile           string `json:"cert_file" yaml:"cert_file"`
+	KeyFile            string `json:"key_file" yaml:"key_file"`
+	ServerName         string `json:"server_name" yaml:"server_name"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+}
+
+type config struct {
+	Url                  string     `json:"url" yaml:"url"`
+	Token                string     `json:"token" yaml:"token"`
+	TokenFile            string     `json:"token_file" yaml:"token_file"`
+	DiscoveryStrategy    string     `json:"discovery_strategy" yaml:"discovery_strategy"`
+	MaxRetries           int        `json:"max_retries" yaml:"max_retries"`
+	ProbeInterval        duration   `json:"probe_interval" yaml:"probe_interval"`
+	Region               string     `json:"region" yaml:"region"`
+	Zone                 string     `json:"zone" yaml:"zone"`
+	Timeout              duration   `json:"timeout" yaml:"timeout"`
+	RefreshInterval      duration   `json:"refresh_interval" yaml:"refresh_interval"`
+	MaxElapsedTime       duration   `json:"max_elapsed_time" yaml:"max_elapsed_time"`
+	RetryTooManyRequests bool       `json:"retry_too_many_requests" yaml:"retry_too_many_requests"`
+	BatchLimit           int        `json:"batch_limit" yaml:"batch_limit"`
+	BatchConcurrency     int        `json:"batch_concurrency" yaml:"batch_concurrency"`
+	BatchPartialResults  bool       `json:"batch_partial_results" yaml:"batch_partial_results"`
+	StrictCheck          bool       `json:"strict_check" yaml:"strict_check"`
+	CheckPointer         string     `json:"check_pointer" yaml:"check_pointer"`
+	FallbackGateways     []string   `json:"fallback_gateways" yaml:"fallback_gateways"`
+	GatewaysFile         string     `json:"gateways_file" yaml:"gateways_file"`
+	Tls                  *tlsConfig `json:"tls" yaml:"tls"`
+
+	// Maps fields to the keys they were read from, for error messages.
+	keys func(field string) string
+}
+
+// A duration is written as a string such as `5s` or `1m30s`.
+type duration time.Duration
+
+func (d *duration) UnmarshalText(text []byte) error {
+	value, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = duration(value)
+
+	return nil
+}
+
+// SettingsFromEnv reads settings from `STYRA_RUN_*` environment variables,
+// e.g. `STYRA_RUN_URL` and `STYRA_RUN_TOKEN`. Unset variables keep their
+// defaults. Every missing or malformed value is reported as a *ConfigError.
+func SettingsFromEnv() (*Settings, error) {
+	env := &envReader{}
+
+	config := &config{
+		Url:                  env.string("URL"),
+		Token:                env.string("TOKEN"),
+		TokenFile:            env.string("TOKEN_FILE"),
+		DiscoveryStrategy:    env.string("DISCOVERY_STRATEGY"),
+		MaxRetries:           env.int("MAX_RETRIES"),
+		ProbeInterval:        env.duration("PROBE_INTERVAL"),
+		Region:               env.string("REGION"),
+		Zone:                 env.string("ZONE"),
+		Timeout:              env.duration("TIMEOUT"),
+		RefreshInterval:      env.duration("REFRESH_INTERVAL"),
+		MaxElapsedTime:       env.duration("MAX_ELAPSED_TIME"),
+		RetryTooManyRequests: env.bool("RETRY_TOO_MANY_REQUESTS"),
+		BatchLimit:           env.int("BATCH_LIMIT"),
+		BatchConcurrency:     env.int("BATCH_CONCURRENCY"),
+		BatchPartialResults:  env.bool("BATCH_PARTIAL_RESULTS"),
+		StrictCheck:          env.bool("STRICT_CHECK"),
+		CheckPointer:         env.string("CHECK_POINTER"),
+		FallbackGateways:     env.list("FALLBACK_GATEWAYS"),
+		GatewaysFile:         env.string("GATEWAYS_FILE"),
+		Tls: &tlsConfig{
+			CaFile:             env.string("TLS_CA_FILE"),
+			CertFile:           env.string("TLS_CERT_FILE"),
+			KeyFile:            env.string("TLS_KEY_FILE"),
+			ServerName:         env.string("TLS_SERVER_NAME"),
+			InsecureSkipVerify: env.bool("TLS_INSECURE_SKIP_VERIFY"),
+		},
+
+		keys: func(field string) string {
+			return envPrefix + strings.ToUpper(strings.ReplaceAll(field, ".", "_"))
+		},
+	}
+
+	// Report every problem at once, whether a value couldn't be parsed
+	// or didn't pass validation.
+	settings, err := config.settings()
+	if err := errors.Join(append(env.errs, err)...); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// SettingsFromFile reads settings from a json file if the path ends with
+// `.json`, and from a yaml file otherwise. Keys are the snake case names of
+// the `STYRA_RUN_*` environment variables without the prefix, and tls options
+// are nested under `tls`. Durations are strings such as `5s`. Every missing
+// or malformed value is reported as a *ConfigError.
+func SettingsFromFile(path string) (*Settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &config{
+		keys: func(field string) string {
+			return field
+		},
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		err = decoder.Decode(config)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		err = decoder.Decode(config)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Relative file paths are resolved against the config file.
+	dir := filepath.Dir(path)
+	config.TokenFile = resolve(dir, config.TokenFile)
+	config.GatewaysFile = resolve(dir, config.GatewaysFile)
+
+	if config.Tls != nil {
+		config.Tls.CaFile = resolve(dir, config.Tls.CaFile)
+		config.Tls.CertFile = resolve(dir, config.Tls.CertFile)
+		config.Tls.KeyFile = resolve(dir, config.Tls.KeyFile)
+	}
+
+	return config.settings()
+}
+
+func (c *config) settings() (*Settings, error) {
+	var errs []error
+	invalid := func(field string, err error) {
+		errs = append(errs, &ConfigError{Key: c.keys(field), Err: err})
+	}
+
+	if c.Url == "" {
+		invalid("url", missingValueError)
+	} else if u, err := url.Parse(c.Url); err != nil {
+		invalid("url", err)
+	} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		invalid("url", fmt.Errorf("%w: expected an http or https url", invalidValueError))
+	}
+
+	if c.Token == "" && c.TokenFile == "" {
+		invalid("token", missingValueError)
+	} else if c.Token != "" && c.TokenFile != "" {
+		invalid("token_file", fmt.Errorf("%w: token and token_file are mutually exclusive", invalidValueError))
+	}
+
+	strategy, ok := discoveryStrategies[strings.ToLower(c.DiscoveryStrategy)]
+	if c.DiscoveryStrategy != "" && !ok {
+		invalid("discovery_strategy", fmt.Errorf("%w: expected simple, sorted or affinity", invalidValueError))
+	}
+
+	for _, value := range []struct {
+		field    string
+		negative bool
+	}{
+		{"max_retries", c.MaxRetries < 0},
+		{"probe_interval", c.ProbeInterval < 0},
+		{"timeout", c.Timeout < 0},
+		{"refresh_interval", c.RefreshInterval < 0},
+		{"max_elapsed_time", c.MaxElapsedTime < 0},
+		{"batch_limit", c.BatchLimit < 0},
+		{"batch_concurrency", c.BatchConcurrency < 0},
+	} {
+		if value.negative {
+			invalid(value.field, fmt.Errorf("%w: must not be negative", invalidValueError))
+		}
+	}
+
+	if c.BatchLimit > batchLimit {
+		invalid("batch_limit", fmt.Errorf("%w: must not exceed %d", invalidValueError, batchLimit))
+	}
+
+	for _, gateway := range c.FallbackGateways {
+		if u, err := url.Parse(gateway); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("fallback_gateways", fmt.Errorf("%w: expected http or https urls", invalidValueError))
+			break
+		}
+	}
+
+	if c.CheckPointer != "" && !strings.HasPrefix(c.CheckPointer, "/") {
+		invalid("check_pointer", fmt.Errorf("%w: must start with /", invalidValueError))
+	}
+
+	tlsConfig := c.tls(invalid)
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	settings := &Settings{
+		Token:                c.Token,
+		Url:                  c.Url,
+		DiscoveryStrategy:    strategy,
+		MaxRetries:           c.MaxRetries,
+		ProbeInterval:        time.Duration(c.ProbeInterval),
+		Region:               c.Region,
+		Zone:                 c.Zone,
+		RefreshInterval:      time.Duration(c.RefreshInterval),
+		MaxElapsedTime:       time.Duration(c.MaxElapsedTime),
+		RetryTooManyRequests: c.RetryTooManyRequests,
+		BatchLimit:           c.BatchLimit,
+		BatchConcurrency:     c.BatchConcurrency,
+		BatchPartialResults:  c.BatchPartialResults,
+		StrictCheck:          c.StrictCheck,
+		CheckPointer:         c.CheckPointer,
+		FallbackGateways:     c.FallbackGateways,
+		GatewaysFile:         c.GatewaysFile,
+	}
+
+	if c.TokenFile != "" {
+		settings.TokenSource = FileToken(c.TokenFile)
+	}
+
+	if c.Timeout > 0 || tlsConfig != nil {
+		timeout := rest.DefaultTimeout
+		if c.Timeout > 0 {
+			timeout = time.Duration(c.Timeout)
+		}
+
+		settings.Client = &http.Client{
+			Timeout: timeout,
+		}
+
+		if tlsConfig != nil {
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = tlsConfig
+
+			settings.Client.Transport = transport
+		}
+	}
+
+	return settings, nil
+}
+
+// Build the tls configuration, or nil if no tls options are set.
+// Every problem is passed to invalid, and the config is only
+// usable if there were none.
+func (c *config) tls(invalid func(field string, err error)) *tls.Config {
+	t := c.Tls
+	if t == nil || *t == (tlsConfig{}) {
+		return nil
+	}
+
+	config := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CaFile != "" {
+		if pem, err := os.ReadFile(t.CaFile); err != nil {
+			invalid("tls.ca_file", err)
+		} else if pool := x509.NewCertPool(); !pool.AppendCertsFromPEM(pem) {
+			invalid("tls.ca_file", fmt.Errorf("%w: no pem encoded certificates", invalidValueError))
+		} else {
+			config.RootCAs = pool
+		}
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		invalid("tls.key_file", fmt.Errorf("%w: the certificate and key files must be set together", invalidValueError))
+	} else if t.CertFile != "" {
+		if certificate, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); err != nil {
+			invalid("tls.cert_file", err)
+		} else {
+			config.Certificates = []tls.Certificate{certificate}
+		}
+	}
+
+	return config
+}
+
+func resolve(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+// An envReader reads `STYRA_RUN_*` environment variables
+// and collects errors for malformed values.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) string(name string) string {
+	return os.Getenv(envPrefix + name)
+}
+
+// A list is comma separated.
+func (e *envReader) list(name string) []string {
+	var result []string
+	for _, value := range strings.Split(e.string(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
+func (e *envReader) int(name string) int {
+	value := e.string(name)
+	if value == "" {
+		return 0
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, &ConfigError{Key: envPrefix + name, Err: err})
+	}
+
+	return result
+}
+
+func (e *envReader) bool(name string) bool {
+	value := e.string(name)
+	if value == "" {
+		return false
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, &ConfigError{Key: envPrefix + name, Err: err})
+	}
+
+	return result
+}
+
+func (e *envReader) duration(name string) duration {
+	value := e.string(name)
+	if value == "" {
+		return 0
+	}
+
+	result, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, &ConfigError{Key: envPrefix + name, Err: err})
+	}
+
+	return duration(result)
+}
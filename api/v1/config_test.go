@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// The keys of every *ConfigError joined into err, sorted.
+func configErrorKeys(err error) []string {
+	var keys []string
+
+	var visit func(err error)
+	visit = func(err error) {
+		var configError *ConfigError
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range joined.Unwrap() {
+				visit(err)
+			}
+		} else if errors.As(err, &configError) {
+			keys = append(keys, configError.Key)
+		}
+	}
+
+	visit(err)
+	sort.Strings(keys)
+
+	return keys
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestSettingsFromEnv(t *testing.T) {
+	t.Setenv("STYRA_RUN_URL", "https://example.com/v1/projects/user/project/envs/env")
+	t.Setenv("STYRA_RUN_TOKEN", "token")
+	t.Setenv("STYRA_RUN_DISCOVERY_STRATEGY", "sorted")
+	t.Setenv("STYRA_RUN_TIMEOUT", "3s")
+	t.Setenv("STYRA_RUN_BATCH_LIMIT", "10")
+	t.Setenv("STYRA_RUN_STRICT_CHECK", "true")
+	t.Setenv("STYRA_RUN_FALLBACK_GATEWAYS", "https://a.example.com, https://b.example.com")
+
+	settings, err := SettingsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if settings.Token != "token" || settings.DiscoveryStrategy != Sorted || settings.BatchLimit != 10 || !settings.StrictCheck {
+		t.Errorf("unexpected settings %+v", settings)
+	}
+
+	if settings.Client == nil || settings.Client.Timeout != time.Second*3 {
+		t.Errorf("expected a client with a 3s timeout, got %+v", settings.Client)
+	}
+
+	if expected := []string{"https://a.example.com", "https://b.example.com"}; !reflect.DeepEqual(settings.FallbackGateways, expected) {
+		t.Errorf("expected fallback gateways %v, got %v", expected, settings.FallbackGateways)
+	}
+}
+
+func TestSettingsFromEnvErrors(t *testing.T) {
+	t.Setenv("STYRA_RUN_TOKEN", "token")
+	t.Setenv("STYRA_RUN_TIMEOUT", "soon")
+	t.Setenv("STYRA_RUN_BATCH_LIMIT", "50")
+
+	_, err := SettingsFromEnv()
+
+	// Parse errors are reported along with validation errors.
+	expected := []string{"STYRA_RUN_BATCH_LIMIT", "STYRA_RUN_TIMEOUT", "STYRA_RUN_URL"}
+	if keys := configErrorKeys(err); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected errors for %v, got %v", expected, err)
+	}
+}
+
+func TestSettingsFromFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string]string{
+		"config.yaml": "url: https://example.com\ntoken_file: token\nmax_retries: 5\nrefresh_interval: 1m\n",
+		"config.json": `{"url": "https://example.com", "token_file": "token", "max_retries": 5, "refresh_interval": "1m"}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		settings, err := SettingsFromFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if settings.Url != "https://example.com" || settings.MaxRetries != 5 || settings.RefreshInterval != time.Minute {
+			t.Errorf("%s: unexpected settings %+v", name, settings)
+		}
+
+		// The token file is resolved relative to the config file.
+		if settings.TokenSource == nil {
+			t.Errorf("%s: expected a token source", name)
+		} else if token, err := settings.TokenSource.Token(nil); err != nil || token != "token" {
+			t.Errorf("%s: expected the token from the file, got %q and %v", name, token, err)
+		}
+	}
+}
+
+func TestSettingsFromFileUnknownField(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "url: https://example.com\ntoken: token\nretries: 5\n")
+
+	if _, err := SettingsFromFile(path); err == nil {
+		t.Error("expected unknown fields to be rejected")
+	}
+}
+
+func TestSettingsFromFileTlsErrors(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "url: https://example.com\ntoken: token\ntls:\n  ca_file: missing.pem\n  cert_file: cert.pem\n")
+
+	_, err := SettingsFromFile(path)
+
+	// Every tls problem is reported, not just the first one.
+	expected := []string{"tls.ca_file", "tls.key_file"}
+	if keys := configErrorKeys(err); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected errors for %v, got %v", expected, err)
+	}
+}
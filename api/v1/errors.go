@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"fmt"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/discovery"
+	"github.com/styrainc/styra-run-sdk-go/internal/errors"
+)
+
+// ErrorResponse is the error payload sent by the data plane.
+type ErrorResponse = errors.ErrorResponse
+
+// ClientClosedError is returned for requests made after Close.
+var ClientClosedError = discovery.ClosedError
+
+// BatchError is returned by BatchQuery in partial results mode if some of
+// the batch requests failed. Every query in a failed batch has its Error
+// field set, and every other query has its Result field set.
+type BatchError struct {
+	// The indices of the queries that failed, in ascending order.
+	Indices []int
+
+	// The total number of queries.
+	Total int
+
+	// The first error that occurred.
+	Err error
+}
+
+func (b *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d queries failed: %s", len(b.Indices), b.Total, b.Err)
+}
+
+func (b *BatchError) Unwrap() error {
+	return b.Err
+}
+
+// Convert an error into the format used by the data plane so it can be
+// attached to individual queries. Details sent by the data plane are kept.
+func errorResponse(err error) *errors.ErrorResponse {
+	if httpError, ok := err.(errors.HttpError); ok && httpError.Details() != nil {
+		return httpError.Details()
+	}
+
+	return &errors.ErrorResponse{
+		Code:    "request_failed",
+		Message: err.Error(),
+	}
+}
+
+// UndefinedResultError is returned by Check in strict mode
+// if the policy result, or the selected part of it, is undefined.
+type UndefinedResultError = errors.UndefinedResultError
+
+// NonBooleanResultError is returned by Check in strict mode if the
+// policy result, or the selected part of it, isn't a boolean.
+type NonBooleanResultError = errors.NonBooleanResultError
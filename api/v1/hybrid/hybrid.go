@@ -0,0 +1,306 @@
+package hybrid
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
+	"github.com/styrainc/styra-run-sdk-go/internal/utils"
+)
+
+// A Policy decides what happens to a request if the primary client is
+// unavailable.
+type Policy uint
+
+const (
+	// Send the request to the secondary client. If there's no secondary
+	// client, the primary client's error is returned.
+	Fallback Policy = iota
+
+	// Check returns true. Every other request returns the primary
+	// client's error.
+	FailOpen
+
+	// Check returns false. Every other request returns the primary
+	// client's error.
+	FailClosed
+)
+
+type Settings struct {
+	// The client requests are sent to first. Required.
+	Primary api.Client
+
+	// Optional client requests are sent to if the primary client is
+	// unavailable and the path's policy is Fallback, e.g. a local client.
+	Secondary api.Client
+
+	// The policy for paths without one in Policies. Defaults to Fallback.
+	Policy Policy
+
+	// Optional policies by path. A policy applies to the path itself and
+	// everything nested under it, and the longest matching path wins, e.g.
+	// `rbac` applies to `rbac/allow` unless there's one for `rbac/allow`.
+	Policies map[string]Policy
+
+	// Decides which errors of the primary client mean that it's unavailable.
+	// Defaults to api.DefaultRetryable(true), i.e. 429, 502, 503 and 504
+	// status codes and transport level failures.
+	Unavailable func(err error) bool
+
+	// Whether successful PutData and DeleteData calls are repeated against
+	// the secondary client, so that it has the same data to fall back on.
+	// Failures to do so are logged and otherwise ignored.
+	MirrorWrites bool
+
+	// Optional logger for requests that failed over to the
+	// secondary client or failed open or closed.
+	Logger *slog.Logger
+}
+
+type client struct {
+	settings *Settings
+	policies map[string]Policy
+	logger   *slog.Logger
+}
+
+// New returns a client that sends requests to the primary client, and if it's
+// unavailable, applies the policy of the request's path. Writes are only sent
+// to the primary client, and are never failed over.
+func New(settings *Settings) api.Client {
+	if settings.Unavailable == nil {
+		settings.Unavailable = api.DefaultRetryable(true)
+	}
+
+	policies := make(map[string]Policy, len(settings.Policies))
+	for path, policy := range settings.Policies {
+		policies[strings.Trim(path, "/")] = policy
+	}
+
+	return &client{
+		settings: settings,
+		policies: policies,
+		logger:   logging.OrDiscard(settings.Logger),
+	}
+}
+
+func (c *client) GetData(ctx context.Context, path string, data interface{}) error {
+	err := c.settings.Primary.GetData(ctx, path, data)
+	if secondary := c.fallback(ctx, path, err); secondary != nil {
+		return secondary.GetData(ctx, path, data)
+	}
+
+	return err
+}
+
+func (c *client) PutData(ctx context.Context, path string, data interface{}) error {
+	if err := c.settings.Primary.PutData(ctx, path, data); err != nil {
+		return err
+	}
+
+	if c.mirror() {
+		if err := c.settings.Secondary.PutData(ctx, path, data); err != nil {
+			c.logger.WarnContext(ctx, "styra run write could not be mirrored", "path", path, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *client) DeleteData(ctx context.Context, path string) error {
+	if err := c.settings.Primary.DeleteData(ctx, path); err != nil {
+		return err
+	}
+
+	if c.mirror() {
+		if err := c.settings.Secondary.DeleteData(ctx, path); err != nil {
+			c.logger.WarnContext(ctx, "styra run delete could not be mirrored", "path", path, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *client) Query(ctx context.Context, path string, input, result interface{}) error {
+	err := c.settings.Primary.Query(ctx, path, input, result)
+	if secondary := c.fallback(ctx, path, err); secondary != nil {
+		return secondary.Query(ctx, path, input, result)
+	}
+
+	return err
+}
+
+func (c *client) QueryWithResponse(ctx context.Context, path string, input, result interface{}) (*api.QueryResponse, error) {
+	response, err := c.settings.Primary.QueryWithResponse(ctx, path, input, result)
+	if secondary := c.fallback(ctx, path, err); secondary != nil {
+		return secondary.QueryWithResponse(ctx, path, input, result)
+	}
+
+	return response, err
+}
+
+func (c *client) Check(ctx context.Context, path string, input interface{}) (bool, error) {
+	allowed, err := c.settings.Primary.Check(ctx, path, input)
+	if !c.unavailable(ctx, err) {
+		return allowed, err
+	}
+
+	switch c.policy(path) {
+	case FailOpen:
+		c.logger.WarnContext(ctx, "styra run unavailable, failing open", "path", path, "error", err)
+
+		return true, nil
+	case FailClosed:
+		c.logger.WarnContext(ctx, "styra run unavailable, failing closed", "path", path, "error", err)
+
+		return false, nil
+	}
+
+	if secondary := c.fallback(ctx, path, err); secondary != nil {
+		return secondary.Check(ctx, path, input)
+	}
+
+	return allowed, err
+}
+
+// BatchQuery only fails over if every query's path has the Fallback policy,
+// since queries don't have a result to fail open or closed with.
+func (c *client) BatchQuery(ctx context.Context, queries []api.Query, input interface{}) error {
+	err := c.settings.Primary.BatchQuery(ctx, queries, input)
+	if !c.unavailable(ctx, err) {
+		return err
+	}
+
+	for _, query := range queries {
+		if c.policy(query.Path) != Fallback {
+			return err
+		}
+	}
+
+	if c.settings.Secondary == nil {
+		return err
+	}
+
+	c.logger.WarnContext(ctx, "styra run unavailable, falling back to secondary client", "queries", len(queries), "error", err)
+
+	return c.settings.Secondary.BatchQuery(ctx, queries, input)
+}
+
+func (c *client) Invalidate(path string) {
+	c.settings.Primary.Invalidate(path)
+
+	if c.settings.Secondary != nil {
+		c.settings.Secondary.Invalidate(path)
+	}
+}
+
+func (c *client) InvalidateAll() {
+	c.settings.Primary.InvalidateAll()
+
+	if c.settings.Secondary != nil {
+		c.settings.Secondary.InvalidateAll()
+	}
+}
+
+// Gateway returns the data plane url the primary client currently uses,
+// if it reports one.
+func (c *client) Gateway() string {
+	if primary, ok := c.settings.Primary.(interface{ Gateway() string }); ok {
+		return primary.Gateway()
+	}
+
+	return ""
+}
+
+// Warmup warms up both clients. It only fails if the primary client
+// failed and there's no secondary client that succeeded.
+func (c *client) Warmup(ctx context.Context) error {
+	return c.both(ctx, "warmup", api.Client.Warmup)
+}
+
+// Start starts both clients. It only fails if the primary client
+// failed and there's no secondary client that succeeded.
+func (c *client) Start(ctx context.Context) error {
+	return c.both(ctx, "start", api.Client.Start)
+}
+
+// Ready returns nil if either client is ready, since requests
+// can be failed over to the secondary client.
+func (c *client) Ready() error {
+	err := c.settings.Primary.Ready()
+	if err != nil && c.settings.Secondary != nil && c.settings.Secondary.Ready() == nil {
+		return nil
+	}
+
+	return err
+}
+
+// Close closes both clients.
+func (c *client) Close() {
+	c.settings.Primary.Close()
+
+	if c.settings.Secondary != nil {
+		c.settings.Secondary.Close()
+	}
+}
+
+func (c *client) both(ctx context.Context, name string, fn func(api.Client, context.Context) error) error {
+	err := fn(c.settings.Primary, ctx)
+	if c.settings.Secondary == nil {
+		return err
+	}
+
+	if secondaryErr := fn(c.settings.Secondary, ctx); secondaryErr != nil || err == nil {
+		return err
+	}
+
+	c.logger.WarnContext(ctx, "styra run primary client failed, relying on secondary client", "operation", name, "error", err)
+
+	return nil
+}
+
+// Returns the secondary client if the request failed because the primary
+// client is unavailable, and the path's policy is Fallback.
+func (c *client) fallback(ctx context.Context, path string, err error) api.Client {
+	if c.settings.Secondary == nil || !c.unavailable(ctx, err) || c.policy(path) != Fallback {
+		return nil
+	}
+
+	c.logger.WarnContext(ctx, "styra run unavailable, falling back to secondary client", "path", path, "error", err)
+
+	return c.settings.Secondary
+}
+
+func (c *client) unavailable(ctx context.Context, err error) bool {
+	return err != nil && !utils.ContextDone(ctx) && c.settings.Unavailable(err)
+}
+
+// The policy of the longest path in Policies that path is, or is nested
+// under. The root, i.e. an empty path, is a prefix of every path.
+func (c *client) policy(path string) Policy {
+	path = strings.Trim(path, "/")
+
+	for {
+		if policy, ok := c.policies[path]; ok {
+			return policy
+		}
+
+		i := strings.LastIndex(path, "/")
+		if i < 0 {
+			break
+		}
+
+		path = path[:i]
+	}
+
+	if policy, ok := c.policies[""]; ok {
+		return policy
+	}
+
+	return c.settings.Policy
+}
+
+func (c *client) mirror() bool {
+	return c.settings.MirrorWrites && c.settings.Secondary != nil
+}
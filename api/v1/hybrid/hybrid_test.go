@@ -0,0 +1,298 @@
+package hybrid
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+
+	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+)
+
+var (
+	unavailableError = errors.New("unavailable")
+	rejectedError    = errors.New("rejected")
+)
+
+// A client that answers every request with the same result and error,
+// and records the paths it was asked about.
+type stubClient struct {
+	api.Client
+	allowed bool
+	err     error
+	mutex   sync.Mutex
+	paths   []string
+}
+
+func (s *stubClient) record(path string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.paths = append(s.paths, path)
+
+	return s.err
+}
+
+func (s *stubClient) calls() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.paths)
+}
+
+func (s *stubClient) Check(ctx context.Context, path string, input interface{}) (bool, error) {
+	return s.allowed, s.record(path)
+}
+
+func (s *stubClient) Query(ctx context.Context, path string, input, result interface{}) error {
+	return s.record(path)
+}
+
+func (s *stubClient) BatchQuery(ctx context.Context, queries []api.Query, input interface{}) error {
+	return s.record("batch")
+}
+
+func (s *stubClient) PutData(ctx context.Context, path string, data interface{}) error {
+	return s.record(path)
+}
+
+func (s *stubClient) Warmup(ctx context.Context) error {
+	return s.err
+}
+
+func (s *stubClient) Ready() error {
+	return s.err
+}
+
+func newTestClient(primary, secondary *stubClient, policies map[string]Policy) api.Client {
+	settings := &Settings{
+		Primary:  primary,
+		Policies: policies,
+		Unavailable: func(err error) bool {
+			return errors.Is(err, unavailableError)
+		},
+	}
+
+	// Keep the secondary client a nil interface rather than a nil pointer.
+	if secondary != nil {
+		settings.Secondary = secondary
+	}
+
+	return New(settings)
+}
+
+func TestCheckPolicies(t *testing.T) {
+	policies := map[string]Policy{
+		"/rbac":      FailClosed,
+		"rbac/allow": FailOpen,
+		"rbac/local": Fallback,
+	}
+
+	for _, test := range []struct {
+		path      string
+		allowed   bool
+		err       error
+		secondary bool
+	}{
+		{path: "rbac/allow", allowed: true},
+		{path: "rbac/allow/nested", allowed: true},
+		{path: "rbac/deny", allowed: false},
+		{path: "rbac/local/allow", allowed: true, secondary: true},
+		{path: "other/allow", allowed: true, secondary: true},
+	} {
+		primary := &stubClient{err: unavailableError}
+		secondary := &stubClient{allowed: true}
+
+		client := newTestClient(primary, secondary, policies)
+
+		allowed, err := client.Check(context.Background(), test.path, nil)
+		if allowed != test.allowed || err != test.err {
+			t.Errorf("%s: expected %v and %v, got %v and %v", test.path, test.allowed, test.err, allowed, err)
+		}
+
+		if calls := secondary.calls(); (calls == 1) != test.secondary {
+			t.Errorf("%s: expected secondary to be called %v, got %d calls", test.path, test.secondary, calls)
+		}
+	}
+}
+
+func TestRootPolicy(t *testing.T) {
+	primary := &stubClient{err: unavailableError}
+	secondary := &stubClient{allowed: true}
+
+	client := newTestClient(primary, secondary, map[string]Policy{
+		"":     FailClosed,
+		"rbac": Fallback,
+	})
+
+	// The root policy applies to every path that doesn't have a longer one.
+	if allowed, err := client.Check(context.Background(), "other/allow", nil); allowed || err != nil {
+		t.Errorf("expected to fail closed, got %v and %v", allowed, err)
+	}
+
+	if allowed, err := client.Check(context.Background(), "rbac/allow", nil); !allowed || err != nil {
+		t.Errorf("expected to fall back, got %v and %v", allowed, err)
+	}
+}
+
+func TestPrimaryAvailable(t *testing.T) {
+	for _, err := range []error{nil, rejectedError} {
+		primary := &stubClient{allowed: true, err: err}
+		secondary := &stubClient{}
+
+		client := newTestClient(primary, secondary, map[string]Policy{"": FailClosed})
+
+		// Only failures that mean the primary client is unavailable are
+		// failed over.
+		if allowed, checkErr := client.Check(context.Background(), "allow", nil); !allowed || checkErr != err {
+			t.Errorf("expected the primary result, got %v and %v", allowed, checkErr)
+		}
+
+		if queryErr := client.Query(context.Background(), "allow", nil, nil); queryErr != err {
+			t.Errorf("expected the primary error, got %v", queryErr)
+		}
+
+		if calls := secondary.calls(); calls != 0 {
+			t.Errorf("expected the secondary client not to be called, got %d calls", calls)
+		}
+	}
+}
+
+func TestCancelled(t *testing.T) {
+	primary := &stubClient{err: unavailableError}
+	secondary := &stubClient{allowed: true}
+
+	client := newTestClient(primary, secondary, map[string]Policy{"": FailOpen})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A cancelled request isn't an outage, so it doesn't fail open.
+	if allowed, err := client.Check(ctx, "allow", nil); allowed || err != unavailableError {
+		t.Errorf("expected the primary error, got %v and %v", allowed, err)
+	}
+}
+
+func TestFailOpenQuery(t *testing.T) {
+	primary := &stubClient{err: unavailableError}
+	secondary := &stubClient{}
+
+	client := newTestClient(primary, secondary, map[string]Policy{"": FailOpen})
+
+	// Queries have no result to fail open with.
+	if err := client.Query(context.Background(), "roles", nil, nil); err != unavailableError {
+		t.Errorf("expected the primary error, got %v", err)
+	}
+
+	if calls := secondary.calls(); calls != 0 {
+		t.Errorf("expected the secondary client not to be called, got %d calls", calls)
+	}
+}
+
+func TestNoSecondary(t *testing.T) {
+	client := newTestClient(&stubClient{err: unavailableError}, nil, nil)
+
+	if _, err := client.Check(context.Background(), "allow", nil); err != unavailableError {
+		t.Errorf("expected the primary error, got %v", err)
+	}
+
+	if err := client.BatchQuery(context.Background(), nil, nil); err != unavailableError {
+		t.Errorf("expected the primary error, got %v", err)
+	}
+
+	if err := client.Ready(); err != unavailableError {
+		t.Errorf("expected the primary error, got %v", err)
+	}
+}
+
+func TestBatchQuery(t *testing.T) {
+	policies := map[string]Policy{
+		"rbac": FailOpen,
+	}
+
+	primary := &stubClient{err: unavailableError}
+	secondary := &stubClient{}
+	client := newTestClient(primary, secondary, policies)
+
+	// Every query has to fall back for the batch to fail over.
+	mixed := []api.Query{{Path: "other"}, {Path: "rbac/allow"}}
+	if err := client.BatchQuery(context.Background(), mixed, nil); err != unavailableError {
+		t.Errorf("expected the primary error, got %v", err)
+	}
+
+	if err := client.BatchQuery(context.Background(), []api.Query{{Path: "other"}}, nil); err != nil {
+		t.Errorf("expected the batch to fall back, got %v", err)
+	}
+
+	if calls := secondary.calls(); calls != 1 {
+		t.Errorf("expected the secondary client to be called once, got %d calls", calls)
+	}
+}
+
+func TestWrites(t *testing.T) {
+	primary := &stubClient{err: unavailableError}
+	secondary := &stubClient{}
+	client := New(&Settings{
+		Primary:      primary,
+		Secondary:    secondary,
+		MirrorWrites: true,
+	})
+
+	// Writes are never failed over.
+	if err := client.PutData(context.Background(), "roles", nil); err != unavailableError {
+		t.Errorf("expected the primary error, got %v", err)
+	}
+
+	if calls := secondary.calls(); calls != 0 {
+		t.Errorf("expected the write not to be failed over, got %d calls", calls)
+	}
+
+	// Successful writes are mirrored, and mirroring failures are ignored.
+	primary.err = nil
+	secondary.err = rejectedError
+
+	if err := client.PutData(context.Background(), "roles", nil); err != nil {
+		t.Errorf("expected the write to succeed, got %v", err)
+	}
+
+	if calls := secondary.calls(); calls != 1 {
+		t.Errorf("expected the write to be mirrored, got %d calls", calls)
+	}
+}
+
+func TestDefaultUnavailable(t *testing.T) {
+	primary := &stubClient{
+		err: &url.Error{Op: "Post", URL: "https://gateway", Err: errors.New("connection refused")},
+	}
+	secondary := &stubClient{allowed: true}
+
+	client := New(&Settings{
+		Primary:   primary,
+		Secondary: secondary,
+	})
+
+	// Transport level failures mean that the primary client is unavailable.
+	if allowed, err := client.Check(context.Background(), "allow", nil); !allowed || err != nil {
+		t.Errorf("expected to fall back, got %v and %v", allowed, err)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	client := newTestClient(&stubClient{err: unavailableError}, &stubClient{}, nil)
+
+	// The secondary client is enough to be ready.
+	if err := client.Warmup(context.Background()); err != nil {
+		t.Errorf("expected the secondary client to be relied on, got %v", err)
+	}
+
+	if err := client.Ready(); err != nil {
+		t.Errorf("expected to be ready, got %v", err)
+	}
+
+	client = newTestClient(&stubClient{err: unavailableError}, &stubClient{err: rejectedError}, nil)
+
+	if err := client.Warmup(context.Background()); err != unavailableError {
+		t.Errorf("expected the primary error, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"github.com/styrainc/styra-run-sdk-go/internal/discovery"
+	"github.com/styrainc/styra-run-sdk-go/internal/rest"
+)
+
+// The operation names seen by interceptors.
+const (
+	OperationGetData    = "get_data"
+	OperationPutData    = "put_data"
+	OperationDeleteData = "delete_data"
+	OperationQuery      = "query"
+	OperationBatchQuery = "batch_query"
+	OperationGateways   = discovery.OperationGateways
+	OperationProbe      = discovery.OperationProbe
+)
+
+// Call describes an outgoing request as seen by interceptors.
+type Call = rest.Call
+
+// Invoker sends the request of a call and emits the response.
+type Invoker = rest.Invoker
+
+// Interceptor wraps the sending of a request. It must call next to pass
+// the call on, and sees the response and error that are emitted by it.
+type Interceptor = rest.Interceptor
+
+// Observer is notified of events such as retries, failovers and discovery
+// of data plane urls. Every callback is optional and is called synchronously.
+type Observer = discovery.Observer
+
+// AttemptEvent describes a single request against a data plane url.
+type AttemptEvent = discovery.AttemptEvent
+
+// Gateway describes a data plane url, as emitted by discovery.
+type Gateway = discovery.Gateway
@@ -0,0 +1,114 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Records what every interceptor saw, in the order they saw it.
+type interceptions struct {
+	mutex sync.Mutex
+	seen  []string
+}
+
+func (i *interceptions) interceptor(name string) Interceptor {
+	return func(call *Call, next Invoker) (*http.Response, error) {
+		i.mutex.Lock()
+		i.seen = append(i.seen, name+" "+call.Operation+" "+call.Path)
+		i.mutex.Unlock()
+
+		call.Request.Header.Add("X-Interceptor", name)
+
+		response, err := next(call)
+
+		i.mutex.Lock()
+		if err == nil {
+			i.seen = append(i.seen, name+" "+response.Status)
+		}
+		i.mutex.Unlock()
+
+		return response, err
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	var headers []string
+	var inputs []interface{}
+
+	d := newDataPlane(t)
+	d.handle("/data/allow", func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Values("X-Interceptor")
+		respond(w, http.StatusOK, map[string]interface{}{
+			"result": true,
+		})
+	})
+
+	var i interceptions
+	client := d.client(t, &Settings{
+		Interceptors: []Interceptor{
+			i.interceptor("outer"),
+			i.interceptor("inner"),
+			func(call *Call, next Invoker) (*http.Response, error) {
+				inputs = append(inputs, call.Input)
+				return next(call)
+			},
+		},
+	})
+
+	input := map[string]interface{}{"user": "alice"}
+	if _, err := client.Check(context.Background(), "allow", input); err != nil {
+		t.Fatal(err)
+	}
+
+	// Discovery passes through the interceptors as well, and the first
+	// interceptor is the outermost one.
+	expected := []string{
+		"outer gateways ",
+		"inner gateways ",
+		"inner 200 OK",
+		"outer 200 OK",
+		"outer query allow",
+		"inner query allow",
+		"inner 200 OK",
+		"outer 200 OK",
+	}
+
+	if !reflect.DeepEqual(i.seen, expected) {
+		t.Errorf("expected %v, got %v", expected, i.seen)
+	}
+
+	if expected := []string{"outer", "inner"}; !reflect.DeepEqual(headers, expected) {
+		t.Errorf("expected the request changes of every interceptor, got %v", headers)
+	}
+
+	if len(inputs) != 2 || inputs[0] != nil || !reflect.DeepEqual(inputs[1], input) {
+		t.Errorf("expected the input of the query, got %v", inputs)
+	}
+}
+
+func TestInterceptorError(t *testing.T) {
+	rejectedError := errors.New("rejected")
+
+	d := newDataPlane(t)
+	client := d.client(t, &Settings{
+		Interceptors: []Interceptor{
+			func(call *Call, next Invoker) (*http.Response, error) {
+				if call.Operation == OperationPutData {
+					return nil, rejectedError
+				}
+
+				return next(call)
+			},
+		},
+	})
+
+	// The error emitted by an interceptor is returned as is, and the
+	// request never reaches the data plane.
+	if err := client.PutData(context.Background(), "roles", []string{"admin"}); !errors.Is(err, rejectedError) {
+		t.Errorf("expected the interceptor error, got %v", err)
+	}
+}
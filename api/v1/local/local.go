@@ -0,0 +1,469 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/loader"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
+	"github.com/styrainc/styra-run-sdk-go/internal/utils"
+)
+
+var (
+	noBundleError      = errors.New("no bundle")
+	notLoadedError     = errors.New("bundle not loaded")
+	invalidPathError   = errors.New("invalid path")
+	nonObjectRootError = errors.New("data at the root must be an object")
+)
+
+type Settings struct {
+	// The bundle to evaluate policies against, either a bundle tarball or a
+	// directory containing policies and data. Required.
+	Bundle string
+
+	// Whether policies use the original rego syntax rather than rego v1.
+	// A rego version set in the bundle manifest takes precedence.
+	RegoV0 bool
+
+	// By default, Check returns false for any result other than true. If
+	// set, Check returns an *api.UndefinedResultError for undefined results
+	// and an *api.NonBooleanResultError for results that aren't booleans.
+	StrictCheck bool
+
+	// Optional json pointer, e.g. `/allow`, that selects the boolean
+	// within the result document that Check looks at.
+	CheckPointer string
+
+	// Optional logger for bundle loading.
+	Logger *slog.Logger
+}
+
+type client struct {
+	settings  *Settings
+	logger    *slog.Logger
+	mutex     sync.Mutex
+	compiler  *ast.Compiler
+	store     storage.Store
+	revision  string
+	err       error
+	queries   map[string]*rego.PreparedEvalQuery
+	lifecycle sync.RWMutex
+	closing   bool
+	inflight  sync.WaitGroup
+}
+
+// New returns a client that evaluates policies in process rather than
+// calling Styra Run. Data is kept in memory, so PutData and DeleteData
+// only affect this client. The bundle is loaded by Start or Warmup, or
+// otherwise by the first request.
+func New(settings *Settings) api.Client {
+	return &client{
+		settings: settings,
+		logger:   logging.OrDiscard(settings.Logger),
+		queries:  make(map[string]*rego.PreparedEvalQuery),
+	}
+}
+
+func (c *client) GetData(ctx context.Context, path string, data interface{}) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+
+	defer c.inflight.Done()
+
+	result, defined, err := c.eval(ctx, path, nil)
+	if err != nil || !defined {
+		return err
+	}
+
+	return convert(result, data)
+}
+
+func (c *client) PutData(ctx context.Context, path string, data interface{}) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+
+	defer c.inflight.Done()
+
+	// The store only accepts values that look like decoded json.
+	var value interface{}
+	if err := convert(data, &value); err != nil {
+		return err
+	}
+
+	return c.write(ctx, path, func(txn storage.Transaction, path storage.Path) error {
+		if len(path) == 0 {
+			if _, ok := value.(map[string]interface{}); !ok {
+				return nonObjectRootError
+			}
+
+			return c.store.Write(ctx, txn, storage.ReplaceOp, path, value)
+		}
+
+		if err := storage.MakeDir(ctx, c.store, txn, path[:len(path)-1]); err != nil {
+			return err
+		}
+
+		return c.store.Write(ctx, txn, storage.AddOp, path, value)
+	})
+}
+
+func (c *client) DeleteData(ctx context.Context, path string) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+
+	defer c.inflight.Done()
+
+	return c.write(ctx, path, func(txn storage.Transaction, path storage.Path) error {
+		if len(path) == 0 {
+			return c.store.Write(ctx, txn, storage.ReplaceOp, path, map[string]interface{}{})
+		}
+
+		// Deleting data that doesn't exist isn't an error.
+		if err := c.store.Write(ctx, txn, storage.RemoveOp, path, nil); err != nil && !storage.IsNotFound(err) {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (c *client) Query(ctx context.Context, path string, input, result interface{}) error {
+	_, err := c.QueryWithResponse(ctx, path, input, result)
+	return err
+}
+
+// QueryWithResponse evaluates the query locally. The response only has
+// Revision, which is the bundle revision, and Duration set.
+func (c *client) QueryWithResponse(ctx context.Context, path string, input, result interface{}) (*api.QueryResponse, error) {
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	defer c.inflight.Done()
+
+	start := time.Now()
+
+	value, defined, err := c.eval(ctx, path, input)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.QueryResponse{
+		Revision: c.revision,
+		Duration: time.Since(start),
+	}
+
+	if !defined {
+		return response, nil
+	}
+
+	return response, convert(value, result)
+}
+
+func (c *client) Check(ctx context.Context, path string, input interface{}) (bool, error) {
+	if err := c.acquire(ctx); err != nil {
+		return false, err
+	}
+
+	defer c.inflight.Done()
+
+	result, defined, err := c.eval(ctx, path, input)
+	if err != nil {
+		return false, err
+	}
+
+	return utils.CheckResult(path, result, defined, c.settings.CheckPointer, c.settings.StrictCheck)
+}
+
+// BatchQuery evaluates every query, using the query's input if it has one
+// and the shared input otherwise. Like the remote client, it either fills
+// in every query or none of them.
+func (c *client) BatchQuery(ctx context.Context, queries []api.Query, input interface{}) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+
+	defer c.inflight.Done()
+
+	results := make([]json.RawMessage, len(queries))
+	for i, query := range queries {
+		queryInput := query.Input
+		if queryInput == nil {
+			queryInput = input
+		}
+
+		result, defined, err := c.eval(ctx, query.Path, queryInput)
+		if err != nil {
+			return err
+		}
+
+		if defined {
+			if results[i], err = json.Marshal(result); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, result := range results {
+		queries[i].Error = nil
+		queries[i].Result = nil
+
+		if result != nil {
+			if err := json.Unmarshal(result, &queries[i].Result); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Invalidate is a no-op since decisions aren't cached.
+func (c *client) Invalidate(path string) {
+}
+
+// InvalidateAll is a no-op since decisions aren't cached.
+func (c *client) InvalidateAll() {
+}
+
+// Warmup loads the bundle unless that already succeeded.
+func (c *client) Warmup(ctx context.Context) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+
+	c.inflight.Done()
+
+	return nil
+}
+
+// Start loads the bundle unless that already succeeded.
+func (c *client) Start(ctx context.Context) error {
+	return c.Warmup(ctx)
+}
+
+// Ready returns nil once the bundle is loaded.
+func (c *client) Ready() error {
+	c.lifecycle.RLock()
+	closing := c.closing
+	c.lifecycle.RUnlock()
+
+	if closing {
+		return api.ClientClosedError
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.store != nil {
+		return nil
+	} else if c.err != nil {
+		return c.err
+	}
+
+	return notLoadedError
+}
+
+// Close rejects new requests with api.ClientClosedError and waits
+// for requests in flight to finish.
+func (c *client) Close() {
+	c.lifecycle.Lock()
+	c.closing = true
+	c.lifecycle.Unlock()
+
+	c.inflight.Wait()
+}
+
+// Register a request as in flight, unless we're closing, and make
+// sure the bundle is loaded.
+func (c *client) acquire(ctx context.Context) error {
+	c.lifecycle.RLock()
+
+	if c.closing {
+		c.lifecycle.RUnlock()
+		return api.ClientClosedError
+	}
+
+	c.inflight.Add(1)
+	c.lifecycle.RUnlock()
+
+	if err := c.load(ctx); err != nil {
+		c.inflight.Done()
+		return err
+	}
+
+	return nil
+}
+
+// Load the bundle, compile its policies and seed the store with its data.
+// A failure is retried by the next request.
+func (c *client) load(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.store != nil {
+		return nil
+	}
+
+	if c.err = c.compile(ctx); c.err != nil {
+		c.logger.ErrorContext(ctx, "styra run bundle could not be loaded", "bundle", c.settings.Bundle, "error", c.err)
+		return c.err
+	}
+
+	c.logger.InfoContext(ctx, "styra run bundle loaded", "bundle", c.settings.Bundle, "revision", c.revision)
+
+	return nil
+}
+
+// Must be called with the mutex held.
+func (c *client) compile(ctx context.Context) error {
+	if c.settings.Bundle == "" {
+		return noBundleError
+	}
+
+	version := ast.RegoV1
+	if c.settings.RegoV0 {
+		version = ast.RegoV0
+	}
+
+	b, err := loader.NewFileLoader().
+		WithRegoVersion(version).
+		AsBundle(c.settings.Bundle)
+	if err != nil {
+		return err
+	}
+
+	compiler := ast.NewCompiler()
+	if compiler.Compile(modules(b)); compiler.Failed() {
+		return compiler.Errors
+	}
+
+	data := b.Data
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	c.compiler = compiler
+	c.store = inmem.NewFromObject(data)
+	c.revision = b.Manifest.Revision
+
+	return nil
+}
+
+// Evaluate the document at path. Returns false if it's undefined.
+func (c *client) eval(ctx context.Context, path string, input interface{}) (interface{}, bool, error) {
+	query, err := c.prepare(ctx, path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	options := []rego.EvalOption{}
+	if input != nil {
+		options = append(options, rego.EvalInput(input))
+	}
+
+	results, err := query.Eval(ctx, options...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return nil, false, nil
+	}
+
+	return results[0].Expressions[0].Value, true, nil
+}
+
+// Prepared queries only depend on the policies, not on the data, so
+// they're kept for the lifetime of the client.
+func (c *client) prepare(ctx context.Context, path string) (*rego.PreparedEvalQuery, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if query, ok := c.queries[path]; ok {
+		return query, nil
+	}
+
+	ref := ast.Ref{ast.DefaultRootDocument}
+	for _, segment := range segments(path) {
+		ref = append(ref, ast.StringTerm(segment))
+	}
+
+	query, err := rego.New(
+		rego.ParsedQuery(ast.NewBody(ast.NewExpr(ast.NewTerm(ref)))),
+		rego.Compiler(c.compiler),
+		rego.Store(c.store),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.queries[path] = &query
+
+	return &query, nil
+}
+
+func (c *client) write(ctx context.Context, path string, fn func(txn storage.Transaction, path storage.Path) error) error {
+	p := storage.Path(segments(path))
+
+	for _, segment := range p {
+		if segment == "" {
+			return fmt.Errorf("%w: %s", invalidPathError, path)
+		}
+	}
+
+	txn, err := c.store.NewTransaction(ctx, storage.WriteParams)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(txn, p); err != nil {
+		c.store.Abort(ctx, txn)
+		return err
+	}
+
+	return c.store.Commit(ctx, txn)
+}
+
+func segments(path string) []string {
+	if path = strings.Trim(path, "/"); path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
+
+func modules(b *bundle.Bundle) map[string]*ast.Module {
+	result := make(map[string]*ast.Module, len(b.Modules))
+	for _, module := range b.Modules {
+		result[module.Path] = module.Parsed
+	}
+
+	return result
+}
+
+// Convert between go values by round tripping through json, the same
+// way results are decoded when they come from the data plane.
+func convert(from, to interface{}) error {
+	bytes, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bytes, to)
+}
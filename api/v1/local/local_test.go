@@ -0,0 +1,200 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+)
+
+func newTestClient(t *testing.T, settings *Settings) api.Client {
+	if settings.Bundle == "" {
+		settings.Bundle = "testdata/bundle"
+	}
+
+	client := New(settings)
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func user(name string) map[string]string {
+	return map[string]string{"user": name}
+}
+
+func TestCheck(t *testing.T) {
+	client := newTestClient(t, &Settings{})
+
+	if allowed, err := client.Check(context.Background(), "rbac/allow", user("alice")); !allowed || err != nil {
+		t.Errorf("expected alice to be allowed, got %v and %v", allowed, err)
+	}
+
+	if allowed, err := client.Check(context.Background(), "rbac/allow", user("bob")); allowed || err != nil {
+		t.Errorf("expected bob to be denied, got %v and %v", allowed, err)
+	}
+}
+
+func TestStrictCheck(t *testing.T) {
+	strict := newTestClient(t, &Settings{
+		StrictCheck: true,
+	})
+
+	var undefined *api.UndefinedResultError
+	if _, err := strict.Check(context.Background(), "rbac/missing", nil); !errors.As(err, &undefined) {
+		t.Errorf("expected an undefined result error, got %v", err)
+	}
+
+	var nonBoolean *api.NonBooleanResultError
+	if _, err := strict.Check(context.Background(), "rbac/decision", user("alice")); !errors.As(err, &nonBoolean) {
+		t.Errorf("expected a non boolean result error, got %v", err)
+	}
+
+	pointer := newTestClient(t, &Settings{
+		StrictCheck:  true,
+		CheckPointer: "/allow",
+	})
+
+	if allowed, err := pointer.Check(context.Background(), "rbac/decision", user("alice")); !allowed || err != nil {
+		t.Errorf("expected the pointer to select true, got %v and %v", allowed, err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	client := newTestClient(t, &Settings{})
+
+	var roles []string
+	response, err := client.QueryWithResponse(context.Background(), "rbac/roles", user("bob"), &roles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(roles, []string{"viewer"}) {
+		t.Errorf("expected bob's roles, got %v", roles)
+	}
+
+	if response.Revision != "test" {
+		t.Errorf("expected the bundle revision, got %s", response.Revision)
+	}
+
+	// An undefined result leaves the result alone.
+	roles = nil
+	if err := client.Query(context.Background(), "rbac/roles", user("carol"), &roles); err != nil || roles != nil {
+		t.Errorf("expected an undefined result, got %v and %v", roles, err)
+	}
+}
+
+func TestBatchQuery(t *testing.T) {
+	client := newTestClient(t, &Settings{})
+
+	queries := []api.Query{
+		{Path: "rbac/allow"},
+		{Path: "rbac/allow", Input: user("bob")},
+		{Path: "rbac/roles", Input: user("carol")},
+	}
+
+	// The shared input is used unless the query has its own.
+	if err := client.BatchQuery(context.Background(), queries, user("alice")); err != nil {
+		t.Fatal(err)
+	}
+
+	if queries[0].Result != true || queries[1].Result != false || queries[2].Result != nil {
+		t.Errorf("expected the results in order, got %+v", queries)
+	}
+}
+
+func TestData(t *testing.T) {
+	client := newTestClient(t, &Settings{})
+
+	if err := client.PutData(context.Background(), "roles/carol", []string{"admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Policies see the new data right away.
+	if allowed, err := client.Check(context.Background(), "rbac/allow", user("carol")); !allowed || err != nil {
+		t.Errorf("expected carol to be allowed, got %v and %v", allowed, err)
+	}
+
+	var roles map[string][]string
+	if err := client.GetData(context.Background(), "roles", &roles); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(roles) != 3 || !reflect.DeepEqual(roles["carol"], []string{"admin"}) {
+		t.Errorf("expected carol's roles to be stored, got %v", roles)
+	}
+
+	if err := client.DeleteData(context.Background(), "roles/alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting data that doesn't exist isn't an error.
+	if err := client.DeleteData(context.Background(), "roles/alice"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if allowed, _ := client.Check(context.Background(), "rbac/allow", user("alice")); allowed {
+		t.Error("expected alice to be denied once her roles are deleted")
+	}
+
+	if err := client.PutData(context.Background(), "", []string{"admin"}); !errors.Is(err, nonObjectRootError) {
+		t.Errorf("expected the root to require an object, got %v", err)
+	}
+
+	if err := client.PutData(context.Background(), "roles//alice", []string{"admin"}); !errors.Is(err, invalidPathError) {
+		t.Errorf("expected an invalid path error, got %v", err)
+	}
+}
+
+func TestRegoV0(t *testing.T) {
+	client := newTestClient(t, &Settings{
+		Bundle: "testdata/v0",
+		RegoV0: true,
+	})
+
+	if allowed, err := client.Check(context.Background(), "legacy/allow", user("alice")); !allowed || err != nil {
+		t.Errorf("expected alice to be allowed, got %v and %v", allowed, err)
+	}
+
+	// The same policy doesn't parse as rego v1.
+	if err := newTestClient(t, &Settings{Bundle: "testdata/v0"}).Warmup(context.Background()); err == nil {
+		t.Error("expected the policy not to load as rego v1")
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	missing := newTestClient(t, &Settings{
+		Bundle: "testdata/missing",
+	})
+
+	if err := missing.Warmup(context.Background()); err == nil {
+		t.Error("expected a missing bundle to fail to load")
+	}
+
+	if err := missing.Ready(); err == nil {
+		t.Error("expected the client not to be ready")
+	}
+
+	client := New(&Settings{
+		Bundle: "testdata/bundle",
+	})
+
+	if err := client.Ready(); err != notLoadedError {
+		t.Errorf("expected the bundle not to be loaded yet, got %v", err)
+	}
+
+	if err := client.Warmup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Ready(); err != nil {
+		t.Errorf("expected the client to be ready, got %v", err)
+	}
+
+	client.Close()
+
+	if _, err := client.Check(context.Background(), "rbac/allow", nil); err != api.ClientClosedError {
+		t.Errorf("expected the client to be closed, got %v", err)
+	}
+}
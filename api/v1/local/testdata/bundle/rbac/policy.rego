@@ -0,0 +1,9 @@
+package rbac
+
+default allow := false
+
+allow if "admin" in data.roles[input.user]
+
+roles := data.roles[input.user]
+
+decision := {"allow": allow}
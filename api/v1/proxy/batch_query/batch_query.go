@@ -1,10 +1,13 @@
 package batch_query
 
 import (
+	"log/slog"
 	"net/http"
 
 	api "github.com/styrainc/styra-run-sdk-go/api/v1"
 	"github.com/styrainc/styra-run-sdk-go/api/v1/proxy/shared"
+	"github.com/styrainc/styra-run-sdk-go/internal/errors"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	"github.com/styrainc/styra-run-sdk-go/types"
 )
@@ -20,7 +23,8 @@ type BatchQueryRequest struct {
 }
 
 type BatchQueryResponseItem struct {
-	Result interface{} `json:"result,omitempty"`
+	Result interface{}           `json:"result,omitempty"`
+	Error  *errors.ErrorResponse `json:"error,omitempty"`
 }
 
 type BatchQueryResponse struct {
@@ -33,6 +37,9 @@ type Settings struct {
 
 	// Optional callback to modify query inputs.
 	OnModifyInput shared.OnModifyInput
+
+	// Optional logger for rejected requests.
+	Logger *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -65,6 +72,7 @@ func New(settings *Settings) *types.Proxy {
 		// Allow the user to modify inputs if the callback is set.
 		if settings.OnModifyInput != nil {
 			if input, err := settings.OnModifyInput(r, "", request.Input); err != nil {
+				utils.RecordError(w, err)
 				utils.InternalServerError(w)
 				return
 			} else {
@@ -73,6 +81,7 @@ func New(settings *Settings) *types.Proxy {
 
 			for i := range queries {
 				if input, err := settings.OnModifyInput(r, queries[i].Path, queries[i].Input); err != nil {
+					utils.RecordError(w, err)
 					utils.InternalServerError(w)
 					return
 				} else {
@@ -82,10 +91,13 @@ func New(settings *Settings) *types.Proxy {
 		}
 
 		// Make the request. If an error occurs, and if it's a http error, forward
-		// the payload on from the backend with the appropriate status code.
+		// the payload on from the backend with the appropriate status code. Partial
+		// failures are reported per item instead.
 		if err := settings.Client.BatchQuery(r.Context(), queries, request.Input); err != nil {
-			utils.ForwardHttpError(w, err)
-			return
+			if _, ok := err.(*api.BatchError); !ok {
+				utils.ForwardHttpError(w, err)
+				return
+			}
 		}
 
 		response := &BatchQueryResponse{
@@ -97,6 +109,7 @@ func New(settings *Settings) *types.Proxy {
 				response.Result,
 				&BatchQueryResponseItem{
 					Result: query.Result,
+					Error:  query.Error,
 				},
 			)
 		}
@@ -106,6 +119,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodPost,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "batch_query", handler),
 	}
 }
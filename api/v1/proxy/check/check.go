@@ -1,10 +1,12 @@
 package check
 
 import (
+	"log/slog"
 	"net/http"
 
 	api "github.com/styrainc/styra-run-sdk-go/api/v1"
 	"github.com/styrainc/styra-run-sdk-go/api/v1/proxy/shared"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	"github.com/styrainc/styra-run-sdk-go/types"
 )
@@ -26,6 +28,9 @@ type Settings struct {
 
 	// Optional callback to modify query inputs.
 	OnModifyInput shared.OnModifyInput
+
+	// Optional logger for rejected requests.
+	Logger *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -48,6 +53,7 @@ func New(settings *Settings) *types.Proxy {
 		// Allow the user to modify inputs if the callback is set.
 		if settings.OnModifyInput != nil {
 			if input, err := settings.OnModifyInput(r, path, request.Input); err != nil {
+				utils.RecordError(w, err)
 				utils.InternalServerError(w)
 				return
 			} else {
@@ -70,6 +76,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodPost,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "check", handler),
 	}
 }
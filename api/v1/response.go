@@ -0,0 +1,127 @@
+package v1
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// QueryResponse holds the metadata the data plane sent along with a
+// query result. Fields are empty if the data plane didn't send them.
+type QueryResponse struct {
+	// The id of the decision, which correlates with decision logs.
+	DecisionId string
+
+	// Timings and other metrics reported by the data plane.
+	Metrics map[string]interface{}
+
+	// Provenance information reported by the data plane, such
+	// as the version of the engine and the policy revisions.
+	Provenance map[string]interface{}
+
+	// The policy revision, if the provenance contains exactly one.
+	Revision string
+
+	// The data plane url that answered the query.
+	Url string
+
+	// The round trip time of the request that answered the query.
+	Duration time.Duration
+
+	// Whether the response was served from the decision cache.
+	Cached bool
+}
+
+// A decision is a query response as it's sent by the data plane.
+type decision struct {
+	Result     json.RawMessage        `json:"result"`
+	DecisionId string                 `json:"decision_id"`
+	Metrics    map[string]interface{} `json:"metrics"`
+	Provenance map[string]interface{} `json:"provenance"`
+
+	gateway  string
+	duration time.Duration
+	hit      bool
+}
+
+// Decisions are shared between callers through the cache and
+// request coalescing, so they must never be modified in place.
+func (d *decision) fromCache() *decision {
+	result := *d
+	result.hit = true
+
+	return &result
+}
+
+func (d *decision) response() *QueryResponse {
+	return &QueryResponse{
+		DecisionId: d.DecisionId,
+		Metrics:    copyObject(d.Metrics),
+		Provenance: copyObject(d.Provenance),
+		Revision:   revision(d.Provenance),
+		Url:        d.gateway,
+		Duration:   d.duration,
+		Cached:     d.hit,
+	}
+}
+
+// Decisions are shared, so callers get their own copy of the metadata.
+func copyObject(object map[string]interface{}) map[string]interface{} {
+	if object == nil {
+		return nil
+	}
+
+	return copyValue(object).(map[string]interface{})
+}
+
+func copyValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, nested := range value {
+			result[key] = copyValue(nested)
+		}
+
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, nested := range value {
+			result[i] = copyValue(nested)
+		}
+
+		return result
+	default:
+		return value
+	}
+}
+
+// The revision is either set at the top level of the provenance, or per
+// bundle. If there are several bundles with different revisions, there's
+// no single revision to report.
+func revision(provenance map[string]interface{}) string {
+	if value, ok := provenance["revision"].(string); ok {
+		return value
+	}
+
+	bundles, ok := provenance["bundles"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	result := ""
+	for _, bundle := range bundles {
+		values, ok := bundle.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if value, ok := values["revision"].(string); ok {
+			if result != "" && result != value {
+				return ""
+			}
+
+			result = value
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestQueryWithResponse(t *testing.T) {
+	d := newDataPlane(t)
+	d.handle("/data/allow", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("metrics") != "true" || r.URL.Query().Get("provenance") != "true" {
+			t.Errorf("expected metrics and provenance to be requested, got %s", r.URL.RawQuery)
+		}
+
+		respond(w, http.StatusOK, map[string]interface{}{
+			"result":      true,
+			"decision_id": "decision",
+			"metrics": map[string]interface{}{
+				"timer_rego_query_eval_ns": 1,
+			},
+			"provenance": map[string]interface{}{
+				"bundles": map[string]interface{}{
+					"policies": map[string]interface{}{
+						"revision": "abc",
+					},
+				},
+			},
+		})
+	})
+
+	client := d.client(t, &Settings{})
+
+	var result bool
+	response, err := client.QueryWithResponse(context.Background(), "allow", nil, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result {
+		t.Error("expected the result to be decoded")
+	}
+
+	if response.DecisionId != "decision" || response.Revision != "abc" || response.Url != d.URL || response.Cached {
+		t.Errorf("unexpected response %+v", response)
+	}
+
+	if response.Metrics["timer_rego_query_eval_ns"] != float64(1) {
+		t.Errorf("expected metrics, got %v", response.Metrics)
+	}
+}
@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"github.com/styrainc/styra-run-sdk-go/internal/discovery"
+)
+
+// CircuitBreakerSettings controls when a data plane url is skipped. See
+// the README for a description of the individual settings.
+type CircuitBreakerSettings = discovery.BreakerSettings
+
+// HedgingSettings controls when read-only requests are also sent to the
+// next data plane url. See the README for a description of the individual
+// settings.
+type HedgingSettings = discovery.HedgeSettings
+
+// DefaultRetryable retries on 502, 503 and 504 status codes and on transport
+// level failures, such as refused connections, dns failures, tls handshake
+// errors and timeouts. If tooManyRequests is set, it also retries on 429.
+func DefaultRetryable(tooManyRequests bool) func(err error) bool {
+	return discovery.DefaultRetryable(tooManyRequests)
+}
+
+func retryable(settings *Settings) discovery.Retryable {
+	if settings.Retryable != nil {
+		return settings.Retryable
+	}
+
+	return discovery.DefaultRetryable(settings.RetryTooManyRequests)
+}
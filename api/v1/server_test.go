@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// A fake data plane that answers discovery requests with its own url, so
+// that clients send every other request to it as well.
+type dataPlane struct {
+	*httptest.Server
+	mux *http.ServeMux
+
+	// How many discovery requests were received, and whether
+	// they're currently answered with 503 Service Unavailable.
+	discoveries int32
+	unavailable int32
+}
+
+func newDataPlane(t *testing.T) *dataPlane {
+	d := &dataPlane{
+		mux: http.NewServeMux(),
+	}
+
+	d.Server = httptest.NewServer(d.mux)
+	t.Cleanup(d.Close)
+
+	d.handle("/gateways", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&d.discoveries, 1)
+
+		if atomic.LoadInt32(&d.unavailable) == 1 {
+			respond(w, http.StatusServiceUnavailable, map[string]string{
+				"code":    "unavailable",
+				"message": "try again later",
+			})
+
+			return
+		}
+
+		respond(w, http.StatusOK, map[string]interface{}{
+			"result": []map[string]string{
+				{"gateway_url": d.URL},
+			},
+		})
+	})
+
+	return d
+}
+
+func (d *dataPlane) handle(pattern string, handler http.HandlerFunc) {
+	d.mux.HandleFunc(pattern, handler)
+}
+
+// Returns a client for the data plane. Url and Token are filled in.
+func (d *dataPlane) client(t *testing.T, settings *Settings) Client {
+	settings.Url = d.URL
+	if settings.Token == "" && settings.TokenSource == nil {
+		settings.Token = "token"
+	}
+
+	client := New(settings)
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func respond(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
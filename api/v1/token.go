@@ -0,0 +1,33 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/auth"
+)
+
+// TokenSource emits the token used for every request. If a request is
+// rejected with 401 unauthorized, the token is refreshed, and the request
+// is retried once if that emitted a different token.
+type TokenSource = auth.TokenSource
+
+// StaticToken always emits the same token.
+func StaticToken(token string) TokenSource {
+	return auth.Static(token)
+}
+
+// FileToken reads the token from a file and reads it again whenever the file
+// changes, e.g. a kubernetes secret mounted as a volume.
+func FileToken(path string) TokenSource {
+	return auth.File(path)
+}
+
+// EnvToken reads the token from an environment variable for every request.
+func EnvToken(name string) TokenSource {
+	return auth.Env(name)
+}
+
+// TokenFunc calls fn for every request. It's up to fn to cache tokens.
+func TokenFunc(fn func(ctx context.Context) (string, error)) TokenSource {
+	return auth.Func(fn)
+}
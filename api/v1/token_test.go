@@ -0,0 +1,156 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	rerrors "github.com/styrainc/styra-run-sdk-go/internal/errors"
+)
+
+// Serves queries that only accept the token `valid`, and records the
+// token of every request, discovery included.
+func newAuthDataPlane(t *testing.T) (*dataPlane, func() []string) {
+	var mutex sync.Mutex
+	var tokens []string
+
+	d := newDataPlane(t)
+	d.handle("/data/allow", func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		mutex.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			respond(w, http.StatusUnauthorized, map[string]string{
+				"code":    "unauthorized",
+				"message": "invalid token",
+			})
+
+			return
+		}
+
+		respond(w, http.StatusOK, map[string]interface{}{
+			"result": true,
+		})
+	})
+
+	return d, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return append([]string(nil), tokens...)
+	}
+}
+
+func TestTokenRefreshedAfterUnauthorized(t *testing.T) {
+	d, tokens := newAuthDataPlane(t)
+
+	// The token is rotated after the first query went out.
+	var mutex sync.Mutex
+	token := "stale"
+	client := d.client(t, &Settings{
+		TokenSource: TokenFunc(func(ctx context.Context) (string, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			return token, nil
+		}),
+	})
+
+	if _, err := client.Check(context.Background(), "allow", nil); !rerrors.IsHttpError(err, http.StatusUnauthorized) {
+		t.Fatalf("expected the query to be rejected, got %v", err)
+	}
+
+	mutex.Lock()
+	token = "valid"
+	mutex.Unlock()
+
+	if allowed, err := client.Check(context.Background(), "allow", nil); !allowed || err != nil {
+		t.Fatalf("expected the query to be allowed, got %v and %v", allowed, err)
+	}
+
+	// A refresh that emits the same token isn't retried.
+	if expected := []string{"Bearer stale", "Bearer valid"}; !reflect.DeepEqual(tokens(), expected) {
+		t.Errorf("expected %v, got %v", expected, tokens())
+	}
+}
+
+// A source whose token only changes when it's refreshed.
+type rotatingSource struct {
+	mutex   sync.Mutex
+	token   string
+	rotated string
+}
+
+func (r *rotatingSource) Token(ctx context.Context) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.token, nil
+}
+
+func (r *rotatingSource) Refresh(ctx context.Context) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.token = r.rotated
+
+	return r.token, nil
+}
+
+func TestTokenRetriedOnce(t *testing.T) {
+	d, tokens := newAuthDataPlane(t)
+	client := d.client(t, &Settings{
+		TokenSource: &rotatingSource{
+			token:   "stale",
+			rotated: "valid",
+		},
+	})
+
+	// The rejected call is retried once with the refreshed token.
+	if allowed, err := client.Check(context.Background(), "allow", nil); !allowed || err != nil {
+		t.Fatalf("expected the query to be allowed, got %v and %v", allowed, err)
+	}
+
+	if expected := []string{"Bearer stale", "Bearer valid"}; !reflect.DeepEqual(tokens(), expected) {
+		t.Errorf("expected %v, got %v", expected, tokens())
+	}
+
+}
+
+func TestTokenRevoked(t *testing.T) {
+	d, tokens := newAuthDataPlane(t)
+	client := d.client(t, &Settings{
+		TokenSource: &rotatingSource{
+			token:   "stale",
+			rotated: "revoked",
+		},
+	})
+
+	// If the refreshed token is rejected as well, the call fails.
+	if _, err := client.Check(context.Background(), "allow", nil); !rerrors.IsHttpError(err, http.StatusUnauthorized) {
+		t.Fatalf("expected the query to be rejected, got %v", err)
+	}
+
+	if expected := []string{"Bearer stale", "Bearer revoked"}; !reflect.DeepEqual(tokens(), expected) {
+		t.Errorf("expected %v, got %v", expected, tokens())
+	}
+}
+
+func TestTokenSourceError(t *testing.T) {
+	tokenError := errors.New("no token")
+
+	d := newDataPlane(t)
+	client := d.client(t, &Settings{
+		TokenSource: TokenFunc(func(ctx context.Context) (string, error) {
+			return "", tokenError
+		}),
+	})
+
+	if _, err := client.Check(context.Background(), "allow", nil); !errors.Is(err, tokenError) {
+		t.Errorf("expected the token source error, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// DecodeError is returned by the typed helpers if a result can't be
+// decoded into the requested type.
+type DecodeError struct {
+	// The path of the query or data.
+	Path string
+
+	// The index of the query for batches, or -1 otherwise.
+	Index int
+
+	// The underlying decoding error.
+	Err error
+}
+
+func (d *DecodeError) Error() string {
+	if d.Index >= 0 {
+		return fmt.Sprintf("could not decode result %d for %s: %s", d.Index, d.Path, d.Err)
+	}
+
+	return fmt.Sprintf("could not decode result for %s: %s", d.Path, d.Err)
+}
+
+func (d *DecodeError) Unwrap() error {
+	return d.Err
+}
+
+// BatchResult is the typed counterpart of a Query after a batch query.
+type BatchResult[T any] struct {
+	Path   string
+	Result T
+	Error  *ErrorResponse
+}
+
+// QueryAs executes a policy rule query and decodes the result into a value
+// of type T. If the result is undefined, the zero value is returned.
+func QueryAs[T any](ctx context.Context, client Client, path string, input interface{}) (T, error) {
+	var raw json.RawMessage
+	var result T
+
+	if err := client.Query(ctx, path, input, &raw); err != nil {
+		return result, err
+	}
+
+	return result, decodeAs(raw, path, -1, &result)
+}
+
+// GetDataAs retrieves data and decodes it into a value of type T. If
+// there's no data, the zero value is returned.
+func GetDataAs[T any](ctx context.Context, client Client, path string) (T, error) {
+	var raw json.RawMessage
+	var result T
+
+	if err := client.GetData(ctx, path, &raw); err != nil {
+		return result, err
+	}
+
+	return result, decodeAs(raw, path, -1, &result)
+}
+
+// BatchQueryAs executes multiple queries at once and decodes every result
+// into a value of type T. The order of the queries is preserved. If a result
+// can't be decoded, the remaining results are still decoded and a
+// *DecodeError for the first mismatch is returned. Otherwise, any error
+// returned by BatchQuery, e.g. a *BatchError, is passed through.
+func BatchQueryAs[T any](ctx context.Context, client Client, queries []Query, input interface{}) ([]*BatchResult[T], error) {
+	err := client.BatchQuery(ctx, queries, input)
+	if err != nil {
+		if _, ok := err.(*BatchError); !ok {
+			return nil, err
+		}
+	}
+
+	var mismatch error
+	result := make([]*BatchResult[T], len(queries))
+	for i, query := range queries {
+		item := &BatchResult[T]{
+			Path:  query.Path,
+			Error: query.Error,
+		}
+
+		if query.Result != nil {
+			if err := convert(query.Result, query.Path, i, &item.Result); err != nil && mismatch == nil {
+				mismatch = err
+			}
+		}
+
+		result[i] = item
+	}
+
+	if mismatch != nil {
+		return result, mismatch
+	}
+
+	return result, err
+}
+
+func decodeAs(raw json.RawMessage, path string, index int, value interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(raw, value); err != nil {
+		return &DecodeError{
+			Path:  path,
+			Index: index,
+			Err:   err,
+		}
+	}
+
+	return nil
+}
+
+// Batch results have already been decoded generically, so
+// round trip them through json to get them into shape.
+func convert(source interface{}, path string, index int, value interface{}) error {
+	raw, err := json.Marshal(source)
+	if err != nil {
+		return &DecodeError{
+			Path:  path,
+			Index: index,
+			Err:   err,
+		}
+	}
+
+	return decodeAs(raw, path, index, value)
+}
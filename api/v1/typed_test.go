@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type roles struct {
+	Roles []string `json:"roles"`
+}
+
+func newTypedDataPlane(t *testing.T) *dataPlane {
+	d := newBatchDataPlane(t)
+	d.handle("/data/roles", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, map[string]interface{}{
+			"result": map[string]interface{}{
+				"roles": []string{"admin", "viewer"},
+			},
+		})
+	})
+	d.handle("/data/undefined", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, map[string]interface{}{})
+	})
+
+	return d
+}
+
+func TestQueryAs(t *testing.T) {
+	client := newTypedDataPlane(t).client(t, &Settings{})
+
+	result, err := QueryAs[roles](context.Background(), client, "roles", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Roles) != 2 || result.Roles[0] != "admin" {
+		t.Errorf("expected the decoded roles, got %+v", result)
+	}
+
+	// An undefined result is the zero value.
+	if result, err := QueryAs[*roles](context.Background(), client, "undefined", nil); result != nil || err != nil {
+		t.Errorf("expected the zero value, got %v and %v", result, err)
+	}
+
+	var decodeError *DecodeError
+	if _, err := QueryAs[string](context.Background(), client, "roles", nil); !errors.As(err, &decodeError) {
+		t.Fatalf("expected a decode error, got %v", err)
+	}
+
+	if decodeError.Path != "roles" || decodeError.Index != -1 {
+		t.Errorf("expected the path and no index, got %+v", decodeError)
+	}
+}
+
+func TestGetDataAs(t *testing.T) {
+	client := newTypedDataPlane(t).client(t, &Settings{})
+
+	result, err := GetDataAs[map[string][]string](context.Background(), client, "roles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result["roles"]) != 2 {
+		t.Errorf("expected the decoded roles, got %v", result)
+	}
+
+	if _, err := GetDataAs[[]int](context.Background(), client, "roles"); !errors.As(err, new(*DecodeError)) {
+		t.Errorf("expected a decode error, got %v", err)
+	}
+}
+
+func TestBatchQueryAs(t *testing.T) {
+	client := newTypedDataPlane(t).client(t, &Settings{})
+
+	// Every result is its path, so they all decode into strings.
+	results, err := BatchQueryAs[string](context.Background(), client, newBatchQueries("a", "b"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].Result != "a" || results[1].Result != "b" || results[1].Path != "b" {
+		t.Errorf("expected the results in order, got %+v", results)
+	}
+
+	// Mismatches are reported for the first query that doesn't decode, and
+	// the other results are still filled in.
+	mismatched, err := BatchQueryAs[int](context.Background(), client, newBatchQueries("a", "b"), nil)
+
+	var decodeError *DecodeError
+	if !errors.As(err, &decodeError) || decodeError.Index != 0 || decodeError.Path != "a" {
+		t.Fatalf("expected a decode error for the first query, got %v", err)
+	}
+
+	if len(mismatched) != 2 {
+		t.Errorf("expected every result, got %+v", mismatched)
+	}
+}
+
+func TestBatchQueryAsPartialResults(t *testing.T) {
+	client := newTypedDataPlane(t).client(t, &Settings{
+		BatchLimit:          1,
+		BatchPartialResults: true,
+	})
+
+	results, err := BatchQueryAs[string](context.Background(), client, newBatchQueries("a", "fail"), nil)
+
+	// The batch error is passed through along with the results that were
+	// filled in.
+	if !errors.As(err, new(*BatchError)) {
+		t.Fatalf("expected a batch error, got %v", err)
+	}
+
+	if results[0].Result != "a" || results[0].Error != nil {
+		t.Errorf("expected the first result, got %+v", results[0])
+	}
+
+	if results[1].Error == nil {
+		t.Errorf("expected the second query to have failed, got %+v", results[1])
+	}
+}
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWarmup(t *testing.T) {
+	d, _ := newCountingDataPlane(t, nil)
+	atomic.StoreInt32(&d.unavailable, 1)
+
+	client := d.client(t, &Settings{
+		// Long enough that only Warmup retries discovery.
+		DiscoveryBackoff: ExponentialBackoff(time.Hour, time.Hour),
+	})
+
+	if err := client.Warmup(context.Background()); err == nil {
+		t.Fatal("expected warmup to fail while discovery is unavailable")
+	}
+
+	// Requests fail fast with the cached failure.
+	if _, err := client.Check(context.Background(), "allow", nil); err == nil {
+		t.Error("expected the check to fail")
+	}
+
+	if n := atomic.LoadInt32(&d.discoveries); n != 1 {
+		t.Errorf("expected requests not to retry discovery while backing off, got %d discoveries", n)
+	}
+
+	// Warmup ignores the cached failure.
+	atomic.StoreInt32(&d.unavailable, 0)
+
+	if err := client.Warmup(context.Background()); err != nil {
+		t.Fatalf("expected warmup to succeed, got %v", err)
+	}
+
+	if err := client.Warmup(context.Background()); err != nil {
+		t.Fatalf("expected warmup to succeed, got %v", err)
+	}
+
+	check(t, client)
+
+	if n := atomic.LoadInt32(&d.discoveries); n != 2 {
+		t.Errorf("expected discovery to run once more, got %d discoveries", n)
+	}
+}
+
+func TestWarmupConcurrent(t *testing.T) {
+	d, _ := newCountingDataPlane(t, nil)
+
+	client := d.client(t, &Settings{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := client.Warmup(context.Background()); err != nil {
+				t.Errorf("expected warmup to succeed, got %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&d.discoveries); n != 1 {
+		t.Errorf("expected concurrent warmups to share a discovery, got %d discoveries", n)
+	}
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	emptyTokenError = errors.New("empty token")
+)
+
+// A TokenSource emits the token used to authenticate against Styra Run. It's
+// consulted for every request. If a request is rejected with 401 unauthorized,
+// the token is refreshed, and the request is retried once if that emitted a
+// different token.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+	Refresh(ctx context.Context) (string, error)
+}
+
+type static struct {
+	token string
+}
+
+// Static always emits the same token.
+func Static(token string) TokenSource {
+	return &static{
+		token: token,
+	}
+}
+
+func (s *static) Token(ctx context.Context) (string, error) {
+	return s.token, nil
+}
+
+func (s *static) Refresh(ctx context.Context) (string, error) {
+	return s.token, nil
+}
+
+type file struct {
+	path    string
+	token   string
+	modTime time.Time
+	size    int64
+	mutex   sync.Mutex
+}
+
+// File reads the token from a file, e.g. a mounted kubernetes secret. The
+// file is read again whenever it changes, so tokens can be rotated without
+// restarting. Surrounding whitespace is trimmed.
+func File(path string) TokenSource {
+	return &file{
+		path: path,
+	}
+}
+
+func (f *file) Token(ctx context.Context) (string, error) {
+	return f.read(false)
+}
+
+func (f *file) Refresh(ctx context.Context) (string, error) {
+	return f.read(true)
+}
+
+func (f *file) read(force bool) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+
+	if !force && f.token != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.token, nil
+	}
+
+	bytes, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(bytes))
+	if token == "" {
+		return "", fmt.Errorf("%w in %s", emptyTokenError, f.path)
+	}
+
+	f.token = token
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+
+	return f.token, nil
+}
+
+type env struct {
+	name string
+}
+
+// Env reads the token from an environment variable on every request.
+func Env(name string) TokenSource {
+	return &env{
+		name: name,
+	}
+}
+
+func (e *env) Token(ctx context.Context) (string, error) {
+	if token := os.Getenv(e.name); token != "" {
+		return token, nil
+	}
+
+	return "", fmt.Errorf("%w in %s", emptyTokenError, e.name)
+}
+
+func (e *env) Refresh(ctx context.Context) (string, error) {
+	return e.Token(ctx)
+}
+
+type callback struct {
+	fn func(ctx context.Context) (string, error)
+}
+
+// Func calls fn for every request, and again when refreshing.
+func Func(fn func(ctx context.Context) (string, error)) TokenSource {
+	return &callback{
+		fn: fn,
+	}
+}
+
+func (c *callback) Token(ctx context.Context) (string, error) {
+	return c.fn(ctx)
+}
+
+func (c *callback) Refresh(ctx context.Context) (string, error) {
+	return c.fn(ctx)
+}
+
+type tokenKey struct{}
+
+// WithToken returns a context that carries the token a call is made with,
+// so that every attempt of the call uses the same token, and a rejected
+// token can be told apart from a refreshed one.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// FromContext returns the token carried by the context, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey{}).(string)
+	return token, ok
+}
+
+// Bearer emits the authorization header value for a token.
+func Bearer(token string) string {
+	return "Bearer " + token
+}
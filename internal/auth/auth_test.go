@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeToken(t *testing.T, path, token string, modTime time.Time) {
+	if err := os.WriteFile(path, []byte(token), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	modTime := time.Now().Add(-time.Hour)
+
+	writeToken(t, path, " first\n", modTime)
+
+	source := File(path)
+	if token, err := source.Token(context.Background()); err != nil || token != "first" {
+		t.Fatalf("expected the trimmed token, got %q and %v", token, err)
+	}
+
+	// A rotated secret is picked up once the file changes.
+	writeToken(t, path, "second", modTime.Add(time.Minute))
+
+	if token, err := source.Token(context.Background()); err != nil || token != "second" {
+		t.Errorf("expected the rotated token, got %q and %v", token, err)
+	}
+
+	// If the file looks the same, only a refresh reads it again.
+	writeToken(t, path, "thirds", modTime.Add(time.Minute))
+
+	if token, _ := source.Token(context.Background()); token != "second" {
+		t.Errorf("expected the cached token, got %q", token)
+	}
+
+	if token, err := source.Refresh(context.Background()); err != nil || token != "thirds" {
+		t.Errorf("expected the refreshed token, got %q and %v", token, err)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+
+	if _, err := File(path).Token(context.Background()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+
+	writeToken(t, path, "\n", time.Now())
+
+	if _, err := File(path).Token(context.Background()); !errors.Is(err, emptyTokenError) {
+		t.Errorf("expected an empty token error, got %v", err)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	source := Env("STYRA_RUN_TEST_TOKEN")
+
+	t.Setenv("STYRA_RUN_TEST_TOKEN", "")
+
+	if _, err := source.Token(context.Background()); !errors.Is(err, emptyTokenError) {
+		t.Errorf("expected an empty token error, got %v", err)
+	}
+
+	t.Setenv("STYRA_RUN_TEST_TOKEN", "token")
+
+	if token, err := source.Refresh(context.Background()); err != nil || token != "token" {
+		t.Errorf("expected the token, got %q and %v", token, err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("expected no token")
+	}
+
+	if token, ok := FromContext(WithToken(context.Background(), "token")); !ok || token != "token" {
+		t.Errorf("expected the token, got %q", token)
+	}
+}
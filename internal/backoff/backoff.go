@@ -0,0 +1,123 @@
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	multiplier = 2
+
+	// Uncapped delays stop growing here, roughly 97 years, so that
+	// multiplying them never overflows.
+	ceiling = time.Duration(math.MaxInt64 / 3)
+)
+
+// A Policy decides how long to wait before retrying a request.
+type Policy interface {
+	// Delay returns the delay before the given retry, starting at 1. The
+	// previous delay is zero for the first retry.
+	Delay(retry int, previous time.Duration) time.Duration
+}
+
+type constant struct {
+	delay time.Duration
+}
+
+// Constant waits the same amount of time before every retry.
+func Constant(delay time.Duration) Policy {
+	return &constant{
+		delay: delay,
+	}
+}
+
+func (c *constant) Delay(retry int, previous time.Duration) time.Duration {
+	return c.delay
+}
+
+type exponential struct {
+	base time.Duration
+	max  time.Duration
+}
+
+// Exponential doubles the delay before every retry, starting
+// at base and never exceeding max. A max of zero means no cap.
+func Exponential(base, max time.Duration) Policy {
+	return &exponential{
+		base: base,
+		max:  max,
+	}
+}
+
+func (e *exponential) Max() time.Duration {
+	return e.max
+}
+
+func (e *exponential) Delay(retry int, previous time.Duration) time.Duration {
+	delay := e.base
+	for i := 1; i < retry && delay > 0 && delay < ceiling; i++ {
+		delay *= multiplier
+
+		if e.max > 0 && delay >= e.max {
+			return e.max
+		}
+	}
+
+	return capped(delay, e.max)
+}
+
+type decorrelated struct {
+	base time.Duration
+	max  time.Duration
+}
+
+// DecorrelatedJitter picks a random delay between base and three times
+// the previous delay, never exceeding max. A max of zero means no cap.
+// This spreads retries from many clients apart, which avoids having
+// them all hit the data plane at the same time after an outage.
+func DecorrelatedJitter(base, max time.Duration) Policy {
+	return &decorrelated{
+		base: base,
+		max:  max,
+	}
+}
+
+func (d *decorrelated) Max() time.Duration {
+	return d.max
+}
+
+func (d *decorrelated) Delay(retry int, previous time.Duration) time.Duration {
+	if previous < d.base {
+		previous = d.base
+	} else if previous > ceiling {
+		previous = ceiling
+	}
+
+	upper := previous * 3
+	if upper <= d.base {
+		return capped(d.base, d.max)
+	}
+
+	delay := d.base + time.Duration(rand.Int63n(int64(upper-d.base)))
+
+	return capped(delay, d.max)
+}
+
+// Max returns the longest delay the policy emits, or zero if the
+// policy doesn't have one.
+func Max(policy Policy) time.Duration {
+	if limited, ok := policy.(interface{ Max() time.Duration }); ok {
+		return limited.Max()
+	}
+
+	return 0
+}
+
+func capped(delay, max time.Duration) time.Duration {
+	if max > 0 && delay > max {
+		return max
+	}
+
+	return delay
+}
@@ -0,0 +1,68 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponential(t *testing.T) {
+	policy := Exponential(time.Second, time.Second*5)
+
+	for i, expected := range []time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 5, time.Second * 5} {
+		if delay := policy.Delay(i+1, 0); delay != expected {
+			t.Errorf("retry %d: expected %s, got %s", i+1, expected, delay)
+		}
+	}
+}
+
+func TestExponentialUncapped(t *testing.T) {
+	policy := Exponential(time.Second, 0)
+
+	previous := time.Duration(0)
+	for _, retry := range []int{1, 10, 30, 40, 64, 100, 1 << 30} {
+		delay := policy.Delay(retry, previous)
+		if delay < previous || delay <= 0 {
+			t.Fatalf("retry %d: expected the delay to keep growing, got %s after %s", retry, delay, previous)
+		}
+
+		previous = delay
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	policy := DecorrelatedJitter(time.Second, time.Second*10)
+
+	previous := time.Duration(0)
+	for retry := 1; retry < 100; retry++ {
+		delay := policy.Delay(retry, previous)
+		if delay < time.Second || delay > time.Second*10 {
+			t.Fatalf("retry %d: expected a delay between 1s and 10s, got %s", retry, delay)
+		}
+
+		previous = delay
+	}
+}
+
+func TestDecorrelatedJitterUncapped(t *testing.T) {
+	policy := DecorrelatedJitter(time.Second, 0)
+
+	previous := time.Duration(0)
+	for retry := 1; retry < 200; retry++ {
+		delay := policy.Delay(retry, previous)
+		if delay < time.Second {
+			t.Fatalf("retry %d: expected at least 1s, got %s", retry, delay)
+		}
+
+		previous = delay
+	}
+}
+
+func TestMax(t *testing.T) {
+	if max := Max(Exponential(time.Second, time.Minute)); max != time.Minute {
+		t.Errorf("expected 1m, got %s", max)
+	}
+
+	if max := Max(Constant(time.Second)); max != 0 {
+		t.Errorf("expected no max, got %s", max)
+	}
+}
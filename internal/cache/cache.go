@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTTL        = time.Second * 10
+	DefaultMaxEntries = 10000
+)
+
+type Settings struct {
+	// How long an entry is kept. Defaults to 10 seconds.
+	TTL time.Duration
+
+	// The maximum number of entries. The least recently used
+	// entry is evicted when it's exceeded. Defaults to 10000.
+	MaxEntries int
+}
+
+// A Cache is a size bounded lru cache whose entries expire. Every entry
+// belongs to a path, which is used to invalidate groups of entries.
+type Cache interface {
+	Get(key string) (interface{}, bool)
+
+	// Generation changes whenever entries are invalidated. It's read
+	// before fetching a value that's put into the cache afterwards.
+	Generation() uint64
+
+	// Put is a no-op if entries were invalidated since generation was
+	// read, since the value may have been fetched before the change that
+	// caused the invalidation.
+	Put(key, path string, value interface{}, generation uint64)
+
+	Invalidate(path string)
+	InvalidateAll()
+}
+
+type entry struct {
+	key     string
+	path    string
+	value   interface{}
+	expires time.Time
+}
+
+type cache struct {
+	settings   *Settings
+	entries    map[string]*list.Element
+	lru        *list.List
+	generation uint64
+	mutex      sync.Mutex
+}
+
+func New(settings *Settings) Cache {
+	if settings.TTL <= 0 {
+		settings.TTL = DefaultTTL
+	}
+
+	if settings.MaxEntries <= 0 {
+		settings.MaxEntries = DefaultMaxEntries
+	}
+
+	return &cache{
+		settings: settings,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (c *cache) Get(key string) (interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*entry)
+	if time.Now().After(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+
+	return entry.value, true
+}
+
+func (c *cache) Generation() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.generation
+}
+
+func (c *cache) Put(key, path string, value interface{}, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	c.entries[key] = c.lru.PushFront(
+		&entry{
+			key:     key,
+			path:    path,
+			value:   value,
+			expires: time.Now().Add(c.settings.TTL),
+		},
+	)
+
+	for c.lru.Len() > c.settings.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Invalidate removes every entry whose path is the given path or is nested
+// under it. For example, `rbac` invalidates `rbac/manage/allow`.
+func (c *cache) Invalidate(path string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+
+	prefix := strings.Trim(path, "/")
+
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+
+		if matches(element.Value.(*entry).path, prefix) {
+			c.remove(element)
+		}
+
+		element = next
+	}
+}
+
+func (c *cache) InvalidateAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+func (c *cache) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*entry).key)
+	c.lru.Remove(element)
+}
+
+func matches(path, prefix string) bool {
+	path = strings.Trim(path, "/")
+
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiry(t *testing.T) {
+	c := New(&Settings{
+		TTL: time.Millisecond * 20,
+	})
+
+	c.Put("key", "path", "value", c.Generation())
+
+	if value, ok := c.Get("key"); !ok || value != "value" {
+		t.Fatalf("expected value, got %v", value)
+	}
+
+	time.Sleep(time.Millisecond * 30)
+
+	if _, ok := c.Get("key"); ok {
+		t.Error("expected the entry to expire")
+	}
+}
+
+func TestEviction(t *testing.T) {
+	c := New(&Settings{
+		MaxEntries: 2,
+	})
+
+	c.Put("a", "a", 1, c.Generation())
+	c.Put("b", "b", 2, c.Generation())
+
+	// Using a makes b the least recently used entry.
+	c.Get("a")
+	c.Put("c", "c", 3, c.Generation())
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	c := New(&Settings{})
+
+	for _, path := range []string{"rbac/manage/allow", "rbac/roles", "rbacs/allow", "other"} {
+		c.Put(path, path, path, c.Generation())
+	}
+
+	c.Invalidate("/rbac/")
+
+	for path, kept := range map[string]bool{
+		"rbac/manage/allow": false,
+		"rbac/roles":        false,
+		"rbacs/allow":       true,
+		"other":             true,
+	} {
+		if _, ok := c.Get(path); ok != kept {
+			t.Errorf("expected %s to be kept: %v", path, kept)
+		}
+	}
+
+	c.InvalidateAll()
+
+	if _, ok := c.Get("other"); ok {
+		t.Error("expected every entry to be invalidated")
+	}
+}
+
+func TestPutAfterInvalidate(t *testing.T) {
+	c := New(&Settings{})
+
+	generation := c.Generation()
+	c.Invalidate("unrelated")
+	c.Put("key", "path", "value", generation)
+
+	if _, ok := c.Get("key"); ok {
+		t.Error("expected a value fetched before an invalidation not to be cached")
+	}
+
+	c.Put("key", "path", "value", c.Generation())
+
+	if _, ok := c.Get("key"); !ok {
+		t.Error("expected a value fetched after the invalidation to be cached")
+	}
+}
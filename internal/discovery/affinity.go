@@ -0,0 +1,105 @@
+package discovery
+
+import (
+	"context"
+	"os"
+	"sort"
+)
+
+var (
+	regionVariables = []string{"AWS_REGION", "AWS_DEFAULT_REGION"}
+	zoneVariables   = []string{"AWS_AVAILABILITY_ZONE", "AWS_AVAILABILITY_ZONE_ID"}
+)
+
+type AffinitySettings struct {
+	// The caller's aws region, e.g. `us-east-1`. If empty, it's
+	// read from the `AWS_REGION` or `AWS_DEFAULT_REGION` variables.
+	Region string
+
+	// The caller's aws availability zone name or id, e.g. `us-east-1a`
+	// or `use1-az1`. If empty, it's read from the `AWS_AVAILABILITY_ZONE`
+	// or `AWS_AVAILABILITY_ZONE_ID` variables.
+	Zone string
+
+	// Optional breaker used to skip unavailable gateways.
+	Breaker Breaker
+}
+
+type affinity struct {
+	settings *AffinitySettings
+	ring     ring
+}
+
+// NewAffinityStrategy prefers gateways in the caller's aws availability
+// zone, then gateways in the caller's region, and then everything else.
+// Within each group, the original order is preserved. In the event of a
+// failure, the next gateway is tried in a round robin fashion.
+func NewAffinityStrategy(settings *AffinitySettings) Strategy {
+	if settings.Region == "" {
+		settings.Region = lookupEnv(regionVariables)
+	}
+
+	if settings.Zone == "" {
+		settings.Zone = lookupEnv(zoneVariables)
+	}
+
+	return &affinity{
+		settings: settings,
+		ring:     newRing(settings.Breaker),
+	}
+}
+
+func (a *affinity) Init(ctx context.Context, gateways []*Gateway) error {
+	ordered := make([]*Gateway, len(gateways))
+	copy(ordered, gateways)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return a.rank(ordered[i]) < a.rank(ordered[j])
+	})
+
+	a.ring.set(urls(ordered))
+
+	return nil
+}
+
+func (a *affinity) Next() {
+	a.ring.next()
+}
+
+func (a *affinity) Gateway() string {
+	return a.ring.gateway()
+}
+
+func (a *affinity) Gateways() []string {
+	return a.ring.gateways()
+}
+
+func (a *affinity) Close() {
+}
+
+// Lower is better: 0 for the same zone, 1 for the same region, 2 otherwise.
+func (a *affinity) rank(gateway *Gateway) int {
+	if gateway.Aws == nil {
+		return 2
+	}
+
+	if zone := a.settings.Zone; zone != "" && (gateway.Aws.Zone == zone || gateway.Aws.ZoneId == zone) {
+		return 0
+	}
+
+	if region := a.settings.Region; region != "" && gateway.Aws.Region == region {
+		return 1
+	}
+
+	return 2
+}
+
+func lookupEnv(names []string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
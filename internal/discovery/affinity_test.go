@@ -0,0 +1,77 @@
+package discovery
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+var affinityGateways = []*Gateway{
+	{Url: "https://other", Aws: &Aws{Region: "eu-west-1", ZoneId: "euw1-az1", Zone: "eu-west-1a"}},
+	{Url: "https://unknown"},
+	{Url: "https://region", Aws: &Aws{Region: "us-east-1", ZoneId: "use1-az2", Zone: "us-east-1b"}},
+	{Url: "https://zone", Aws: &Aws{Region: "us-east-1", ZoneId: "use1-az1", Zone: "us-east-1a"}},
+}
+
+func TestAffinity(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		settings *AffinitySettings
+		expected []string
+	}{
+		{
+			name:     "zone name",
+			settings: &AffinitySettings{Region: "us-east-1", Zone: "us-east-1a"},
+			expected: []string{"https://zone", "https://region", "https://other", "https://unknown"},
+		},
+		{
+			name:     "zone id",
+			settings: &AffinitySettings{Region: "us-east-1", Zone: "use1-az1"},
+			expected: []string{"https://zone", "https://region", "https://other", "https://unknown"},
+		},
+		{
+			name:     "region",
+			settings: &AffinitySettings{Region: "us-east-1"},
+			expected: []string{"https://region", "https://zone", "https://other", "https://unknown"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// Make sure the settings aren't filled in from the environment.
+			for _, name := range append(regionVariables, zoneVariables...) {
+				t.Setenv(name, "")
+			}
+
+			a := NewAffinityStrategy(test.settings)
+			if err := a.Init(context.Background(), affinityGateways); err != nil {
+				t.Fatal(err)
+			}
+
+			if gateways := a.Gateways(); !reflect.DeepEqual(gateways, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, gateways)
+			}
+		})
+	}
+}
+
+func TestAffinityFromEnv(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "eu-west-1")
+	t.Setenv("AWS_AVAILABILITY_ZONE", "")
+	t.Setenv("AWS_AVAILABILITY_ZONE_ID", "")
+
+	a := NewAffinityStrategy(&AffinitySettings{})
+	if err := a.Init(context.Background(), affinityGateways); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything else keeps its discovery order.
+	expected := []string{"https://other", "https://unknown", "https://region", "https://zone"}
+	if gateways := a.Gateways(); !reflect.DeepEqual(gateways, expected) {
+		t.Errorf("expected %v, got %v", expected, gateways)
+	}
+
+	// The discovered list itself isn't reordered.
+	if affinityGateways[0].Url != "https://other" || affinityGateways[3].Url != "https://zone" {
+		t.Error("expected the discovered gateways to be left alone")
+	}
+}
@@ -0,0 +1,240 @@
+package discovery
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBreakerWindow       = 10
+	DefaultBreakerMinRequests  = 5
+	DefaultBreakerFailureRatio = 0.5
+	DefaultBreakerCooldown     = time.Second * 30
+)
+
+type BreakerSettings struct {
+	// How many recent outcomes are tracked per gateway. Defaults to 10.
+	Window int
+
+	// The minimum number of tracked outcomes before the circuit
+	// can open. Defaults to 5.
+	MinRequests int
+
+	// The ratio of failed outcomes that opens the circuit. Defaults to 0.5.
+	FailureRatio float64
+
+	// How long the circuit stays open before probe requests
+	// are let through again. Defaults to 30 seconds.
+	Cooldown time.Duration
+}
+
+// A Breaker tracks the health of every gateway. Strategies skip
+// gateways that aren't available when choosing one.
+type Breaker interface {
+	// Whether a request could be sent to the gateway. It doesn't change
+	// the state of the gateway's circuit.
+	Available(url string) bool
+
+	// Called right before a request is sent to the gateway. Returns false
+	// if the request must not be sent, e.g. because another request is
+	// already probing the gateway.
+	Acquire(url string) bool
+
+	// Called if an acquired request was abandoned without an outcome.
+	Release(url string)
+
+	Success(url string)
+	Failure(url string)
+}
+
+type circuitState uint
+
+const (
+	closed circuitState = iota
+	open
+	halfOpen
+)
+
+type circuit struct {
+	state    circuitState
+	outcomes []bool
+	index    int
+	count    int
+	until    time.Time
+}
+
+type breaker struct {
+	settings *BreakerSettings
+	circuits map[string]*circuit
+	mutex    sync.Mutex
+}
+
+// NewBreaker opens the circuit of a gateway when the ratio of failures among
+// its recent requests crosses a threshold. While open, the gateway is skipped.
+// Once the cooldown has elapsed, the circuit becomes half open and a single
+// probe request is let through. Its outcome either closes the circuit or
+// opens it for another cooldown period. If the probe is abandoned, the next
+// request probes instead, and if it never reports back, another one is let
+// through after the cooldown. If settings is nil, every gateway is always
+// available.
+func NewBreaker(settings *BreakerSettings) Breaker {
+	if settings == nil {
+		return &noopBreaker{}
+	}
+
+	if settings.Window <= 0 {
+		settings.Window = DefaultBreakerWindow
+	}
+
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = DefaultBreakerMinRequests
+	}
+
+	if settings.MinRequests > settings.Window {
+		settings.MinRequests = settings.Window
+	}
+
+	if settings.FailureRatio <= 0 {
+		settings.FailureRatio = DefaultBreakerFailureRatio
+	}
+
+	if settings.Cooldown <= 0 {
+		settings.Cooldown = DefaultBreakerCooldown
+	}
+
+	return &breaker{
+		settings: settings,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// While the circuit is open, until is the end of the cooldown. While it's
+// half open, until is when the probe in flight is given up on.
+func (b *breaker) Available(url string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c := b.circuit(url)
+
+	return c.state == closed || !time.Now().Before(c.until)
+}
+
+func (b *breaker) Acquire(url string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c := b.circuit(url)
+
+	if c.state == closed {
+		return true
+	} else if time.Now().Before(c.until) {
+		return false
+	}
+
+	c.state = halfOpen
+	c.until = time.Now().Add(b.settings.Cooldown)
+
+	return true
+}
+
+func (b *breaker) Release(url string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if c := b.circuit(url); c.state == halfOpen {
+		c.until = time.Time{}
+	}
+}
+
+func (b *breaker) Success(url string) {
+	b.record(url, true)
+}
+
+func (b *breaker) Failure(url string) {
+	b.record(url, false)
+}
+
+func (b *breaker) record(url string, success bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c := b.circuit(url)
+
+	switch c.state {
+	case halfOpen:
+		if success {
+			b.reset(c)
+		} else {
+			b.trip(c)
+		}
+	case closed:
+		c.outcomes[c.index] = success
+		c.index = (c.index + 1) % len(c.outcomes)
+
+		if c.count < len(c.outcomes) {
+			c.count++
+		}
+
+		if c.count >= b.settings.MinRequests && b.ratio(c) >= b.settings.FailureRatio {
+			b.trip(c)
+		}
+	case open:
+		// Requests that were already in flight when the circuit
+		// opened don't tell us anything new.
+	}
+}
+
+func (b *breaker) circuit(url string) *circuit {
+	c, ok := b.circuits[url]
+	if !ok {
+		c = &circuit{
+			outcomes: make([]bool, b.settings.Window),
+		}
+
+		b.circuits[url] = c
+	}
+
+	return c
+}
+
+func (b *breaker) ratio(c *circuit) float64 {
+	failures := 0
+	for i := 0; i < c.count; i++ {
+		if !c.outcomes[i] {
+			failures++
+		}
+	}
+
+	return float64(failures) / float64(c.count)
+}
+
+func (b *breaker) trip(c *circuit) {
+	c.state = open
+	c.until = time.Now().Add(b.settings.Cooldown)
+}
+
+func (b *breaker) reset(c *circuit) {
+	c.state = closed
+	c.index = 0
+	c.count = 0
+}
+
+type noopBreaker struct {
+}
+
+func (n *noopBreaker) Available(url string) bool {
+	return true
+}
+
+func (n *noopBreaker) Acquire(url string) bool {
+	return true
+}
+
+func (n *noopBreaker) Release(url string) {
+}
+
+func (n *noopBreaker) Success(url string) {
+}
+
+func (n *noopBreaker) Failure(url string) {
+}
@@ -0,0 +1,147 @@
+package discovery
+
+import (
+	"testing"
+	"time"
+)
+
+const testGateway = "https://testGateway"
+
+func newTestBreaker(cooldown time.Duration) Breaker {
+	return NewBreaker(&BreakerSettings{
+		Window:       4,
+		MinRequests:  2,
+		FailureRatio: 0.5,
+		Cooldown:     cooldown,
+	})
+}
+
+func TestBreakerOpens(t *testing.T) {
+	b := newTestBreaker(time.Hour)
+
+	b.Failure(testGateway)
+
+	if !b.Available(testGateway) {
+		t.Fatal("expected the circuit to stay closed below MinRequests")
+	}
+
+	b.Success(testGateway)
+
+	if b.Available(testGateway) {
+		t.Fatal("expected the circuit to open at the failure ratio")
+	}
+
+	if b.Acquire(testGateway) {
+		t.Error("expected no request to be let through while open")
+	}
+
+	// Requests that were already in flight don't change anything.
+	b.Success(testGateway)
+
+	if b.Available(testGateway) {
+		t.Error("expected the circuit to stay open")
+	}
+}
+
+func TestBreakerStaysClosed(t *testing.T) {
+	b := newTestBreaker(time.Hour)
+
+	for n := 0; n < 3; n++ {
+		b.Success(testGateway)
+	}
+
+	b.Failure(testGateway)
+
+	// The window is full, so this pushes out the oldest success.
+	b.Success(testGateway)
+
+	if !b.Available(testGateway) || !b.Acquire(testGateway) {
+		t.Error("expected the circuit to stay closed below the failure ratio")
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := newTestBreaker(time.Millisecond * 20)
+
+	b.Failure(testGateway)
+	b.Failure(testGateway)
+
+	time.Sleep(time.Millisecond * 30)
+
+	// Checking availability must not claim the probe.
+	for n := 0; n < 2; n++ {
+		if !b.Available(testGateway) {
+			t.Fatal("expected a probe to be allowed after the cooldown")
+		}
+	}
+
+	if !b.Acquire(testGateway) {
+		t.Fatal("expected the probe to be let through")
+	}
+
+	if b.Available(testGateway) || b.Acquire(testGateway) {
+		t.Fatal("expected a single probe while half open")
+	}
+
+	b.Success(testGateway)
+
+	if !b.Available(testGateway) || !b.Acquire(testGateway) || !b.Acquire(testGateway) {
+		t.Error("expected a successful probe to close the circuit")
+	}
+}
+
+func TestBreakerProbeFails(t *testing.T) {
+	b := newTestBreaker(time.Millisecond * 20)
+
+	b.Failure(testGateway)
+	b.Failure(testGateway)
+
+	time.Sleep(time.Millisecond * 30)
+
+	if !b.Acquire(testGateway) {
+		t.Fatal("expected the probe to be let through")
+	}
+
+	b.Failure(testGateway)
+
+	if b.Available(testGateway) || b.Acquire(testGateway) {
+		t.Error("expected a failed probe to open the circuit again")
+	}
+
+	time.Sleep(time.Millisecond * 30)
+
+	if !b.Acquire(testGateway) {
+		t.Error("expected another probe after the cooldown")
+	}
+}
+
+func TestBreakerProbeReleased(t *testing.T) {
+	b := newTestBreaker(time.Millisecond * 20)
+
+	b.Failure(testGateway)
+	b.Failure(testGateway)
+
+	time.Sleep(time.Millisecond * 30)
+
+	if !b.Acquire(testGateway) {
+		t.Fatal("expected the probe to be let through")
+	}
+
+	b.Release(testGateway)
+
+	if !b.Available(testGateway) || !b.Acquire(testGateway) {
+		t.Error("expected an abandoned probe to let the next request probe")
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := NewBreaker(nil)
+
+	for n := 0; n < 10; n++ {
+		b.Failure(testGateway)
+	}
+
+	if !b.Available(testGateway) || !b.Acquire(testGateway) {
+		t.Error("expected a disabled breaker to let every request through")
+	}
+}
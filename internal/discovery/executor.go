@@ -4,33 +4,52 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/styrainc/styra-run-sdk-go/internal/auth"
+	"github.com/styrainc/styra-run-sdk-go/internal/backoff"
 	rerrors "github.com/styrainc/styra-run-sdk-go/internal/errors"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/rest"
+	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 )
 
 const (
-	gatewayUrlFormat = "%s/gateways"
-	maxRetries       = 3
+	OperationGateways = "gateways"
+	OperationProbe    = "probe"
 )
 
+const (
+	gatewayUrlFormat     = "%s/gateways"
+	maxRetries           = 3
+	refreshAfterFailures = 3
+	maxRetryAfter        = time.Second * 30
+)
+
+// ClosedError is returned for requests made after Close.
+var ClosedError = errors.New("client is closed")
+
 var (
-	noGatewaysError = errors.New("no gateways")
-	badGatewayCodes = map[int]bool{
+	noGatewaysError         = errors.New("no gateways")
+	notDiscoveredError      = errors.New("gateways not discovered")
+	noReachableGatewayError = errors.New("no reachable gateway")
+	badGatewayCodes         = map[int]bool{
 		502: true, // bad gateway
 		503: true, // service unavailable
 		504: true, // gateway timeout
 	}
 )
 
-// TODO: Add sorted strategy.
-
 type StrategyType uint
 
 const (
 	Simple StrategyType = iota
+	Sorted
+	Affinity
 )
 
 type Aws struct {
@@ -48,97 +67,580 @@ type Strategy interface {
 	Init(ctx context.Context, gateways []*Gateway) error
 	Next()
 	Gateway() string
+	Gateways() []string
+	Close()
 }
 
-type Request func(url string) error
+type Request func(ctx context.Context, url string) error
 
 type ExecutorSettings struct {
-	Token        string
-	Url          string
-	StrategyType StrategyType
-	MaxRetries   int
-	Client       *http.Client
+	TokenSource   auth.TokenSource
+	Url           string
+	StrategyType  StrategyType
+	MaxRetries    int
+	ProbeInterval time.Duration
+	Region        string
+	Zone          string
+	Client        *http.Client
+
+	// How often the gateway list is re-discovered. Disabled if zero.
+	RefreshInterval time.Duration
+
+	// How many consecutive requests must exhaust all of their retries
+	// before the gateway list is re-discovered. Defaults to 3.
+	RefreshAfterFailures int
+
+	// How long to wait between retries. Retries are immediate if nil.
+	Backoff backoff.Policy
+
+	// The maximum time spent retrying a single request. Unbounded if zero.
+	MaxElapsedTime time.Duration
+
+	// How long a failed initial discovery is cached before it's attempted
+	// again. Defaults to exponential backoff from 1 to 30 seconds.
+	InitBackoff backoff.Policy
+
+	// Optional gateways used if the initial discovery fails and
+	// there's no gateway list persisted in GatewaysFile.
+	FallbackGateways []string
+
+	// Optional file the discovered gateway list is persisted to. It's used
+	// if the initial discovery fails, e.g. during a control plane outage.
+	GatewaysFile string
+
+	// Decides which errors are retried on the next gateway. Defaults
+	// to bad gateway status codes and transport level failures.
+	Retryable Retryable
+
+	// Optional circuit breaker settings. Disabled if nil.
+	Breaker *BreakerSettings
+
+	// Optional hedging settings. Disabled if nil.
+	Hedge *HedgeSettings
+
+	// Optional interceptors for discovery and probe requests.
+	Interceptors []rest.Interceptor
+
+	// Optional observers that are notified of executor events.
+	Observers []*Observer
+
+	// Optional logger for retries, gateway switches and discovery failures.
+	Logger *slog.Logger
 }
 
 type Executor interface {
+	Init(ctx context.Context) error
+	Ready() error
 	Try(ctx context.Context, request Request) error
+	Hedge(ctx context.Context, attempt Attempt) (interface{}, error)
+	Gateway() string
+	Close()
 }
 
 type executor struct {
-	settings *ExecutorSettings
-	strategy Strategy
-	mutex    sync.Mutex
+	settings    *ExecutorSettings
+	strategy    Strategy
+	breaker     Breaker
+	latencies   *latencies
+	init        *initializer
+	logger      *slog.Logger
+	mutex       sync.Mutex
+	failures    int32
+	unreachable int32
+	refreshing  int32
+	started     sync.Once
+	lifecycle   sync.RWMutex
+	closing     bool
+	inflight    sync.WaitGroup
+	done        chan struct{}
+	closed      sync.Once
 }
 
 func NewExecutor(settings *ExecutorSettings) Executor {
+	if settings.RefreshAfterFailures <= 0 {
+		settings.RefreshAfterFailures = refreshAfterFailures
+	}
+
+	if settings.Retryable == nil {
+		settings.Retryable = DefaultRetryable(false)
+	}
+
+	if settings.TokenSource == nil {
+		settings.TokenSource = auth.Static("")
+	}
+
+	if settings.MaxRetries <= 0 {
+		settings.MaxRetries = maxRetries
+	}
+
 	return &executor{
-		settings: settings,
+		settings:  settings,
+		breaker:   NewBreaker(settings.Breaker),
+		latencies: newLatencies(settings.Hedge),
+		init:      newInitializer(settings.InitBackoff),
+		logger:    logging.OrDiscard(settings.Logger),
+		done:      make(chan struct{}),
 	}
 }
 
 func (e *executor) Try(ctx context.Context, request Request) error {
+	if err := e.acquire(); err != nil {
+		return err
+	}
+
+	defer e.inflight.Done()
+
 	if err := e.initialized(ctx); err != nil {
 		return err
 	}
 
-	var err error
-	for i := 0; i < e.settings.MaxRetries; i++ {
-		gateway := e.strategy.Gateway()
+	ctx, err := e.authorize(ctx)
+	if err != nil {
+		return err
+	}
 
-		if err = request(gateway); err == nil {
-			return nil
-		} else if httpError, ok := err.(rerrors.HttpError); !ok {
-			return err
-		} else if _, ok := badGatewayCodes[httpError.Code()]; !ok {
+	err = e.try(ctx, request, 0)
+	if ctx, ok := e.reauthorize(ctx, err); ok {
+		err = e.try(ctx, request, 0)
+	}
+
+	e.settle(ctx, err)
+
+	return err
+}
+
+// Keep track of requests that failed on every gateway they tried.
+func (e *executor) settle(ctx context.Context, err error) {
+	if err == nil {
+		atomic.StoreInt32(&e.failures, 0)
+		atomic.StoreInt32(&e.unreachable, 0)
+	} else if utils.ContextDone(ctx) {
+		return
+	} else if !e.settings.Retryable(err) {
+		// A gateway responded, it just didn't like the request.
+		atomic.StoreInt32(&e.unreachable, 0)
+	} else {
+		atomic.StoreInt32(&e.unreachable, 1)
+
+		// Every gateway we tried is unavailable, so the list itself may be
+		// stale. Re-discover in the background rather than blocking callers.
+		if atomic.AddInt32(&e.failures, 1) >= int32(e.settings.RefreshAfterFailures) {
+			atomic.StoreInt32(&e.failures, 0)
+
+			e.logger.WarnContext(ctx, "styra run gateways keep failing, refreshing gateway list")
+
+			go e.refresh(context.Background())
+		}
+	}
+}
+
+// Ready returns nil if the gateway list was discovered and a gateway is
+// reachable, i.e. the most recent request didn't fail on every gateway it
+// tried and the breaker hasn't marked every gateway as unavailable.
+func (e *executor) Ready() error {
+	e.lifecycle.RLock()
+	closing := e.closing
+	e.lifecycle.RUnlock()
+
+	if closing {
+		return ClosedError
+	}
+
+	if done, err := e.init.Ready(); !done {
+		if err != nil {
 			return err
-		} else {
-			e.mutex.Lock()
-
-			// This must be guarded because multiple requests could
-			// be in flight with the same gateway. If both fail, we
-			// want to advance to the next gateway exactly once.
-			if e.strategy.Gateway() == gateway {
-				e.strategy.Next()
+		}
+
+		return notDiscoveredError
+	}
+
+	if atomic.LoadInt32(&e.unreachable) == 1 {
+		return noReachableGatewayError
+	}
+
+	for _, gateway := range e.current().Gateways() {
+		if e.breaker.Available(gateway) {
+			return nil
+		}
+	}
+
+	return noReachableGatewayError
+}
+
+// Gateway returns the gateway that would currently be chosen,
+// or an empty string if the gateway list hasn't been discovered.
+func (e *executor) Gateway() string {
+	if strategy := e.current(); strategy != nil {
+		return strategy.Gateway()
+	}
+
+	return ""
+}
+
+// Close rejects new requests, waits for requests in flight to finish, and
+// then stops background refreshes and releases the current strategy.
+func (e *executor) Close() {
+	e.closed.Do(func() {
+		e.lifecycle.Lock()
+		e.closing = true
+		e.lifecycle.Unlock()
+
+		e.inflight.Wait()
+
+		close(e.done)
+
+		if strategy := e.current(); strategy != nil {
+			strategy.Close()
+		}
+	})
+}
+
+// Register a request as in flight, unless we're closing.
+func (e *executor) acquire() error {
+	e.lifecycle.RLock()
+	defer e.lifecycle.RUnlock()
+
+	if e.closing {
+		return ClosedError
+	}
+
+	e.inflight.Add(1)
+
+	return nil
+}
+
+// Make attempts numbered first and up, until MaxRetries attempts have been
+// made in total. Attempts before first were made elsewhere, e.g. by hedging.
+func (e *executor) try(ctx context.Context, request Request, first int) error {
+	strategy := e.current()
+	start := time.Now()
+
+	var err error
+	var delay time.Duration
+	var failed string
+	for i := first; i < e.settings.MaxRetries; i++ {
+		if i > 0 {
+			delay = e.delay(i, delay, err, strategy.Gateway() == failed)
+
+			if max := e.settings.MaxElapsedTime; max > 0 && time.Since(start)+delay > max {
+				return err
 			}
 
-			e.mutex.Unlock()
+			if err := sleep(ctx, delay); err != nil {
+				return err
+			}
 		}
+
+		gateway := e.claim(strategy.Gateways())
+
+		if err = e.attempt(ctx, strategy, gateway, i, false, request); err == nil || !e.retryable(ctx, err) {
+			return err
+		}
+
+		failed = gateway
 	}
 
 	return err
 }
 
-func (e *executor) initialized(ctx context.Context) error {
-	if e.strategy != nil {
-		return nil
+// Choose the first gateway the breaker lets a request through to. The
+// strategy already skips unavailable gateways, but another request may have
+// claimed the probe of a half open gateway in the meantime. If the breaker
+// rejects every gateway, the first one is used as is.
+func (e *executor) claim(gateways []string) string {
+	if len(gateways) == 0 {
+		return ""
+	}
+
+	for _, gateway := range gateways {
+		if e.breaker.Acquire(gateway) {
+			return gateway
+		}
 	}
 
-	if e.settings.MaxRetries <= 0 {
-		e.settings.MaxRetries = maxRetries
+	return gateways[0]
+}
+
+// Make a single request against a gateway and record the outcome.
+func (e *executor) attempt(ctx context.Context, strategy Strategy, gateway string, number int, hedged bool, request Request) error {
+	start := time.Now()
+
+	err := request(ctx, gateway)
+
+	event := &AttemptEvent{
+		Gateway:  gateway,
+		Attempt:  number,
+		Hedged:   hedged,
+		Duration: time.Since(start),
+		Err:      err,
 	}
 
-	gateways, err := e.gateways(ctx)
+	defer e.notifyAttempt(ctx, event)
+
+	if err == nil {
+		e.breaker.Success(gateway)
+		e.latencies.add(time.Since(start))
+	} else if utils.ContextDone(ctx) {
+		e.breaker.Release(gateway)
+	} else if !e.settings.Retryable(err) {
+		// The gateway responded, it just didn't like the request.
+		e.breaker.Success(gateway)
+	} else {
+		event.Retryable = true
+
+		e.breaker.Failure(gateway)
+		e.mutex.Lock()
+
+		// This must be guarded because multiple requests could
+		// be in flight with the same gateway. If both fail, we
+		// want to advance to the next gateway exactly once.
+		next := ""
+		if strategy.Gateway() == gateway {
+			strategy.Next()
+			next = strategy.Gateway()
+		}
+
+		e.mutex.Unlock()
+
+		if next != "" && next != gateway {
+			e.notifyFailover(ctx, gateway, next)
+		}
+	}
+
+	return err
+}
+
+// Fetch the token once per call, and hand it to every attempt through the
+// context, so that a 401 can be matched with the token that was rejected.
+func (e *executor) authorize(ctx context.Context) (context.Context, error) {
+	token, err := e.settings.TokenSource.Token(ctx)
+	if err != nil {
+		return ctx, err
+	}
+
+	return auth.WithToken(ctx, token), nil
+}
+
+// A 401 may mean that the token was rotated, so refresh it. If that emitted
+// a different token, the returned context carries it and the caller retries
+// the call once. Otherwise, retrying would only be rejected again.
+func (e *executor) reauthorize(ctx context.Context, err error) (context.Context, bool) {
+	if !rerrors.IsHttpError(err, http.StatusUnauthorized) {
+		return ctx, false
+	}
+
+	token, err := e.settings.TokenSource.Refresh(ctx)
+	if err != nil {
+		e.logger.WarnContext(ctx, "styra run token refresh failed", slog.Any("error", err))
+
+		return ctx, false
+	}
+
+	if used, _ := auth.FromContext(ctx); token == used {
+		return ctx, false
+	}
+
+	e.logger.InfoContext(ctx, "styra run token refreshed after unauthorized response")
+
+	return auth.WithToken(ctx, token), true
+}
+
+func (e *executor) retryable(ctx context.Context, err error) bool {
+	return !utils.ContextDone(ctx) && e.settings.Retryable(err)
+}
+
+// The delay before a retry is chosen by the backoff policy, but the server
+// can ask us to wait longer through the `Retry-After` header. The header
+// only speaks for the gateway that sent it, so it's ignored unless the retry
+// goes to the same gateway, and it's capped by the policy's max delay, or
+// 30 seconds if there's none. Without a policy, retries are immediate and
+// the header is ignored.
+func (e *executor) delay(retry int, previous time.Duration, err error, same bool) time.Duration {
+	if e.settings.Backoff == nil {
+		return 0
+	}
+
+	delay := e.settings.Backoff.Delay(retry, previous)
+
+	httpError, ok := err.(rerrors.HttpError)
+	if !ok || !same {
+		return delay
+	}
+
+	limit := backoff.Max(e.settings.Backoff)
+	if limit <= 0 {
+		limit = maxRetryAfter
+	}
+
+	if retryAfter := min(httpError.RetryAfter(), limit); retryAfter > delay {
+		delay = retryAfter
+	}
+
+	return delay
+}
+
+// Init discovers the gateway list unless that already succeeded. Unlike the
+// implicit initialization of the first request, a cached failure is ignored.
+func (e *executor) Init(ctx context.Context) error {
+	if err := e.acquire(); err != nil {
+		return err
+	}
+
+	defer e.inflight.Done()
+
+	return e.init.Do(ctx, true, e.discoverInitial)
+}
+
+func (e *executor) initialized(ctx context.Context) error {
+	return e.init.Do(ctx, false, e.discoverInitial)
+}
+
+func (e *executor) discoverInitial(ctx context.Context) error {
+	strategy, fallback, err := e.discover(ctx, true)
 	if err != nil {
 		return err
-	} else if len(gateways) == 0 {
-		return noGatewaysError
+	}
+
+	e.swap(strategy)
+
+	e.started.Do(func() {
+		if e.settings.RefreshInterval > 0 {
+			go e.loop()
+		}
+
+		if fallback {
+			go e.rediscover()
+		}
+	})
+
+	return nil
+}
+
+func (e *executor) loop() {
+	ticker := time.NewTicker(e.settings.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+			e.refresh(context.Background())
+		}
+	}
+}
+
+// Re-discover the gateway list and swap in a new strategy. Requests in flight
+// keep using the gateway they were handed. If discovery fails, the current
+// strategy is kept. Concurrent refreshes are collapsed into one. Returns
+// whether a new strategy was swapped in.
+func (e *executor) refresh(ctx context.Context) bool {
+	if !atomic.CompareAndSwapInt32(&e.refreshing, 0, 1) {
+		return false
+	}
+
+	defer atomic.StoreInt32(&e.refreshing, 0)
+
+	strategy, _, err := e.discover(ctx, false)
+	if err != nil {
+		return false
+	}
+
+	e.swap(strategy)
+
+	return true
+}
+
+// Discover the gateway list and build a strategy for it. If discovery fails
+// and fallback is set, a persisted or static gateway list is used instead,
+// which is reported through the second return value.
+func (e *executor) discover(ctx context.Context, fallback bool) (Strategy, bool, error) {
+	var gateways []*Gateway
+	authorized, err := e.authorize(ctx)
+	if err == nil {
+		gateways, err = e.gateways(authorized)
+		if authorized, ok := e.reauthorize(authorized, err); ok {
+			gateways, err = e.gateways(authorized)
+		}
+	}
+
+	if err == nil && len(gateways) == 0 {
+		err = noGatewaysError
+	}
+
+	e.notifyDiscovery(ctx, gateways, err)
+
+	fallen := false
+	if err == nil {
+		e.store(ctx, gateways)
+	} else if !fallback || !e.retryable(ctx, err) {
+		// Only fall back if the control plane is unreachable. A rejected
+		// token or an empty gateway list wouldn't be fixed by waiting.
+		return nil, false, err
+	} else if gateways = e.fallback(ctx); gateways == nil {
+		return nil, false, err
+	} else {
+		fallen = true
 	}
 
 	var strategy Strategy
 	switch e.settings.StrategyType {
 	case Simple:
-		strategy = NewSimpleStrategy()
+		strategy = NewSimpleStrategy(e.breaker)
+	case Sorted:
+		strategy = NewSortedStrategy(
+			&SortedSettings{
+				Interval: e.settings.ProbeInterval,
+				Client:   e.settings.Client,
+				Breaker:  e.breaker,
+
+				Interceptors: e.settings.Interceptors,
+			},
+		)
+	case Affinity:
+		strategy = NewAffinityStrategy(
+			&AffinitySettings{
+				Region:  e.settings.Region,
+				Zone:    e.settings.Zone,
+				Breaker: e.breaker,
+			},
+		)
 	default:
-		strategy = NewSimpleStrategy()
+		strategy = NewSimpleStrategy(e.breaker)
 	}
 
 	if err := strategy.Init(ctx, gateways); err != nil {
-		return err
+		return nil, false, err
 	}
 
+	return strategy, fallen, nil
+}
+
+func (e *executor) current() Strategy {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.strategy
+}
+
+func (e *executor) swap(strategy Strategy) {
+	e.mutex.Lock()
+
+	old := e.strategy
 	e.strategy = strategy
 
-	return nil
+	e.mutex.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	// If we were closed while discovering, don't leak the new strategy.
+	select {
+	case <-e.done:
+		strategy.Close()
+	default:
+	}
 }
 
 func (e *executor) gateways(ctx context.Context) ([]*Gateway, error) {
@@ -146,12 +648,21 @@ func (e *executor) gateways(ctx context.Context) ([]*Gateway, error) {
 		Result []*Gateway `json:"result"`
 	}{}
 
+	headers, err := e.bearer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	rest := &rest.Rest{
 		Url:     fmt.Sprintf(gatewayUrlFormat, e.settings.Url),
 		Method:  http.MethodGet,
 		Client:  e.settings.Client,
-		Headers: e.bearer(),
+		Headers: headers,
 		Decoder: rerrors.HttpErrorDecoder(response),
+
+		Operation:    OperationGateways,
+		Gateway:      e.settings.Url,
+		Interceptors: e.settings.Interceptors,
 	}
 	if err := rest.Execute(ctx); err != nil {
 		return nil, err
@@ -160,8 +671,32 @@ func (e *executor) gateways(ctx context.Context) ([]*Gateway, error) {
 	return response.Result, nil
 }
 
-func (e *executor) bearer() map[string]string {
+func (e *executor) bearer(ctx context.Context) (map[string]string, error) {
+	token, ok := auth.FromContext(ctx)
+	if !ok {
+		var err error
+		if token, err = e.settings.TokenSource.Token(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	return map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", e.settings.Token),
+		"Authorization": auth.Bearer(token),
+	}, nil
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
 }
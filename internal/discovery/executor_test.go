@@ -0,0 +1,180 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rerrors "github.com/styrainc/styra-run-sdk-go/internal/errors"
+)
+
+var unavailableError = rerrors.NewHttpError(http.StatusServiceUnavailable, nil)
+
+// A control plane whose gateway list can be changed between discoveries.
+type controlPlane struct {
+	*httptest.Server
+	mutex       sync.Mutex
+	gateways    []string
+	code        int
+	discoveries int32
+}
+
+func newControlPlane(t *testing.T, gateways ...string) *controlPlane {
+	c := &controlPlane{
+		gateways: gateways,
+		code:     http.StatusOK,
+	}
+
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&c.discoveries, 1)
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		if c.code != http.StatusOK {
+			w.WriteHeader(c.code)
+			return
+		}
+
+		result := make([]*Gateway, 0, len(c.gateways))
+		for _, gateway := range c.gateways {
+			result = append(result, &Gateway{Url: gateway})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+	}))
+
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+func (c *controlPlane) set(code int, gateways ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.code = code
+	c.gateways = gateways
+}
+
+func (c *controlPlane) executor(t *testing.T, settings *ExecutorSettings) Executor {
+	settings.Url = c.URL
+
+	e := NewExecutor(settings)
+	t.Cleanup(e.Close)
+
+	return e
+}
+
+func waitForGateway(t *testing.T, e Executor, expected string) {
+	deadline := time.Now().Add(time.Second * 5)
+	for e.Gateway() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected gateway %s, got %s", expected, e.Gateway())
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestExecutorRefreshInterval(t *testing.T) {
+	c := newControlPlane(t, "https://old")
+	e := c.executor(t, &ExecutorSettings{
+		RefreshInterval: time.Millisecond * 20,
+	})
+
+	if err := e.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if gateway := e.Gateway(); gateway != "https://old" {
+		t.Fatalf("expected the discovered gateway, got %s", gateway)
+	}
+
+	c.set(http.StatusOK, "https://new")
+
+	waitForGateway(t, e, "https://new")
+
+	// A failed refresh keeps the current gateway list.
+	c.set(http.StatusServiceUnavailable)
+
+	discoveries := atomic.LoadInt32(&c.discoveries)
+	for atomic.LoadInt32(&c.discoveries) < discoveries+2 {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	if gateway := e.Gateway(); gateway != "https://new" {
+		t.Errorf("expected the gateway list to be kept, got %s", gateway)
+	}
+}
+
+func TestExecutorRefreshAfterFailures(t *testing.T) {
+	c := newControlPlane(t, "https://old")
+	e := c.executor(t, &ExecutorSettings{
+		MaxRetries:           1,
+		RefreshAfterFailures: 2,
+	})
+
+	fail := func(ctx context.Context, url string) error {
+		return unavailableError
+	}
+
+	if err := e.Try(context.Background(), fail); err != unavailableError {
+		t.Fatalf("expected the gateway error, got %v", err)
+	}
+
+	c.set(http.StatusOK, "https://new")
+
+	// A request that succeeds in between resets the count.
+	e.Try(context.Background(), func(ctx context.Context, url string) error {
+		return nil
+	})
+
+	e.Try(context.Background(), fail)
+
+	time.Sleep(time.Millisecond * 50)
+
+	if discoveries := atomic.LoadInt32(&c.discoveries); discoveries != 1 {
+		t.Fatalf("expected no refresh below RefreshAfterFailures, got %d discoveries", discoveries)
+	}
+
+	e.Try(context.Background(), fail)
+
+	waitForGateway(t, e, "https://new")
+}
+
+func TestExecutorRefreshInFlight(t *testing.T) {
+	c := newControlPlane(t, "https://old")
+	e := c.executor(t, &ExecutorSettings{
+		MaxRetries:      1,
+		RefreshInterval: time.Millisecond * 20,
+	})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	// A request in flight keeps the gateway it was handed.
+	result := make(chan string, 1)
+	go e.Try(context.Background(), func(ctx context.Context, url string) error {
+		close(started)
+		<-release
+		result <- url
+		return nil
+	})
+
+	<-started
+
+	c.set(http.StatusOK, "https://new")
+
+	waitForGateway(t, e, "https://new")
+	close(release)
+
+	if url := <-result; url != "https://old" {
+		t.Errorf("expected the request to keep its gateway, got %s", url)
+	}
+}
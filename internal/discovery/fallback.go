@@ -0,0 +1,114 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Persist the gateway list, so that the next process can start even if
+// discovery fails. The file is replaced atomically so that a crash never
+// leaves a truncated list behind. Failures are logged and otherwise ignored.
+func (e *executor) store(ctx context.Context, gateways []*Gateway) {
+	if e.settings.GatewaysFile == "" {
+		return
+	}
+
+	if err := writeGateways(e.settings.GatewaysFile, gateways); err != nil {
+		e.logger.WarnContext(ctx, "styra run gateway list could not be persisted", "file", e.settings.GatewaysFile, "error", err)
+	}
+}
+
+// The gateway list to use if the initial discovery failed. A persisted list
+// is preferred over the static one since it's likely more accurate. Returns
+// nil if neither is available.
+func (e *executor) fallback(ctx context.Context) []*Gateway {
+	if file := e.settings.GatewaysFile; file != "" {
+		gateways, err := readGateways(file)
+		if err == nil && len(gateways) > 0 {
+			e.logger.WarnContext(ctx, "styra run using persisted gateway list", "file", file, "count", len(gateways))
+
+			return gateways
+		}
+
+		if err != nil && !os.IsNotExist(err) {
+			e.logger.WarnContext(ctx, "styra run persisted gateway list could not be read", "file", file, "error", err)
+		}
+	}
+
+	if len(e.settings.FallbackGateways) == 0 {
+		return nil
+	}
+
+	gateways := make([]*Gateway, 0, len(e.settings.FallbackGateways))
+	for _, url := range e.settings.FallbackGateways {
+		gateways = append(gateways, &Gateway{Url: url})
+	}
+
+	e.logger.WarnContext(ctx, "styra run using fallback gateway list", "count", len(gateways))
+
+	return gateways
+}
+
+// Keep trying to discover the gateway list while running on a fallback,
+// backing off like the initial discovery does.
+func (e *executor) rediscover() {
+	var delay time.Duration
+	for retry := 1; ; retry++ {
+		delay = e.init.backoff.Delay(retry, delay)
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-e.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if e.refresh(context.Background()) {
+			return
+		}
+	}
+}
+
+func readGateways(file string) ([]*Gateway, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var gateways []*Gateway
+	if err := json.Unmarshal(bytes, &gateways); err != nil {
+		return nil, err
+	}
+
+	return gateways, nil
+}
+
+func writeGateways(file string, gateways []*Gateway) error {
+	bytes, err := json.Marshal(gateways)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(bytes); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), file)
+}
@@ -0,0 +1,105 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/backoff"
+	rerrors "github.com/styrainc/styra-run-sdk-go/internal/errors"
+)
+
+func TestFallbackGatewaysFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gateways.json")
+
+	// A successful discovery is persisted.
+	c := newControlPlane(t, "https://persisted")
+	if err := c.executor(t, &ExecutorSettings{GatewaysFile: file}).Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if gateways, err := readGateways(file); err != nil || len(gateways) != 1 || gateways[0].Url != "https://persisted" {
+		t.Fatalf("expected the gateway list to be persisted, got %v and %v", gateways, err)
+	}
+
+	// The next executor starts from the persisted list, which is preferred
+	// over the static one, while the control plane is unavailable.
+	c = newControlPlane(t)
+	c.set(http.StatusServiceUnavailable)
+
+	e := c.executor(t, &ExecutorSettings{
+		GatewaysFile:     file,
+		FallbackGateways: []string{"https://static"},
+		InitBackoff:      backoff.Constant(time.Millisecond * 10),
+	})
+
+	if err := e.Init(context.Background()); err != nil {
+		t.Fatalf("expected to fall back, got %v", err)
+	}
+
+	if gateway := e.Gateway(); gateway != "https://persisted" {
+		t.Errorf("expected the persisted gateway, got %s", gateway)
+	}
+
+	// Discovery keeps being retried in the background.
+	c.set(http.StatusOK, "https://discovered")
+
+	waitForGateway(t, e, "https://discovered")
+}
+
+func TestFallbackGateways(t *testing.T) {
+	c := newControlPlane(t)
+	c.set(http.StatusServiceUnavailable)
+
+	e := c.executor(t, &ExecutorSettings{
+		GatewaysFile:     filepath.Join(t.TempDir(), "missing.json"),
+		FallbackGateways: []string{"https://a", "https://b"},
+	})
+
+	if err := e.Init(context.Background()); err != nil {
+		t.Fatalf("expected to fall back, got %v", err)
+	}
+
+	if gateway := e.Gateway(); gateway != "https://a" {
+		t.Errorf("expected the first fallback gateway, got %s", gateway)
+	}
+}
+
+func TestFallbackNotRetryable(t *testing.T) {
+	for _, test := range []struct {
+		code     int
+		expected func(err error) bool
+	}{
+		{
+			code: http.StatusUnauthorized,
+			expected: func(err error) bool {
+				return rerrors.IsHttpError(err, http.StatusUnauthorized)
+			},
+		},
+		{
+			// An empty gateway list.
+			code: http.StatusOK,
+			expected: func(err error) bool {
+				return err == noGatewaysError
+			},
+		},
+	} {
+		c := newControlPlane(t)
+		c.set(test.code)
+
+		e := c.executor(t, &ExecutorSettings{
+			FallbackGateways: []string{"https://static"},
+		})
+
+		// Waiting wouldn't fix these, so there's no fallback.
+		if err := e.Init(context.Background()); !test.expected(err) {
+			t.Errorf("%d: expected the discovery error, got %v", test.code, err)
+		}
+
+		if gateway := e.Gateway(); gateway != "" {
+			t.Errorf("%d: expected no gateway, got %s", test.code, gateway)
+		}
+	}
+}
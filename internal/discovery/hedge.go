@@ -0,0 +1,241 @@
+package discovery
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHedgePercentile = 0.95
+	DefaultHedgeDelay      = time.Millisecond * 50
+	DefaultHedgeSamples    = 100
+	minHedgeSamples        = 10
+)
+
+type HedgeSettings struct {
+	// The percentile of recent request latencies after which the request is
+	// also sent to the next gateway, e.g. 0.95. Defaults to 0.95.
+	Percentile float64
+
+	// The delay used until enough latencies have been observed. It's also
+	// the lower bound for the percentile based delay. Defaults to 50ms.
+	Delay time.Duration
+
+	// How many recent latencies are kept. Defaults to 100.
+	Samples int
+}
+
+// An Attempt makes a single request against a gateway. Since hedged attempts
+// run concurrently, they must not write to shared state. Instead, the value
+// returned by the winning attempt is handed back to the caller.
+type Attempt func(ctx context.Context, url string) (interface{}, error)
+
+type outcome struct {
+	value interface{}
+	err   error
+}
+
+// Hedge sends the request to the current gateway. If it hasn't answered in
+// time, or if it failed, the request is also sent to the next gateway and the
+// first successful response wins. The other request is cancelled. If both
+// fail, the request is retried as usual. If hedging is disabled, this is
+// the same as Try.
+func (e *executor) Hedge(ctx context.Context, attempt Attempt) (interface{}, error) {
+	if err := e.acquire(); err != nil {
+		return nil, err
+	}
+
+	defer e.inflight.Done()
+
+	if err := e.initialized(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx, err := e.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := e.hedge(ctx, attempt)
+	if ctx, ok := e.reauthorize(ctx, err); ok {
+		value, err = e.hedge(ctx, attempt)
+	}
+
+	e.settle(ctx, err)
+
+	return value, err
+}
+
+func (e *executor) hedge(ctx context.Context, attempt Attempt) (interface{}, error) {
+	strategy := e.current()
+
+	var value interface{}
+	request := func(ctx context.Context, url string) error {
+		var err error
+		value, err = attempt(ctx, url)
+		return err
+	}
+
+	gateways := strategy.Gateways()
+	if e.settings.Hedge == nil || len(gateways) < 2 || e.settings.MaxRetries < 2 {
+		err := e.try(ctx, request, 0)
+		return value, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	outcomes := make(chan *outcome, 2)
+	launch := func(gateway string, number int) {
+		go func() {
+			var value interface{}
+			err := e.attempt(ctx, strategy, gateway, number, number > 0, func(ctx context.Context, url string) error {
+				var err error
+				value, err = attempt(ctx, url)
+				return err
+			})
+
+			outcomes <- &outcome{
+				value: value,
+				err:   err,
+			}
+		}()
+	}
+
+	first := e.claim(gateways)
+	launch(first, 0)
+
+	timer := time.NewTimer(e.latencies.percentile())
+	defer timer.Stop()
+
+	var err error
+	launched, pending := 1, 1
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if launched == 1 {
+				launch(e.claim(others(gateways, first)), 1)
+				launched, pending = 2, pending+1
+			}
+		case outcome := <-outcomes:
+			pending--
+
+			if outcome.err == nil {
+				return outcome.value, nil
+			}
+
+			err = outcome.err
+
+			if !e.retryable(ctx, err) {
+				return nil, err
+			}
+
+			// Don't wait for the timer if the first gateway failed fast.
+			if launched == 1 {
+				launch(e.claim(others(gateways, first)), 1)
+				launched, pending = 2, pending+1
+			}
+		}
+	}
+
+	// Both gateways failed, so fall back to regular retries for the attempts
+	// that are left. The strategy has already moved on from the gateways
+	// that failed.
+	if launched >= e.settings.MaxRetries {
+		return nil, err
+	}
+
+	if err = e.try(ctx, request, launched); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// All gateways except the given one.
+func others(gateways []string, gateway string) []string {
+	result := make([]string, 0, len(gateways))
+	for _, other := range gateways {
+		if other != gateway {
+			result = append(result, other)
+		}
+	}
+
+	return result
+}
+
+// A latencies keeps track of recent successful request latencies
+// in order to determine when to hedge.
+type latencies struct {
+	settings *HedgeSettings
+	samples  []time.Duration
+	index    int
+	count    int
+	mutex    sync.Mutex
+}
+
+func newLatencies(settings *HedgeSettings) *latencies {
+	if settings == nil {
+		return &latencies{}
+	}
+
+	if settings.Percentile <= 0 || settings.Percentile > 1 {
+		settings.Percentile = DefaultHedgePercentile
+	}
+
+	if settings.Delay <= 0 {
+		settings.Delay = DefaultHedgeDelay
+	}
+
+	if settings.Samples <= 0 {
+		settings.Samples = DefaultHedgeSamples
+	}
+
+	return &latencies{
+		settings: settings,
+		samples:  make([]time.Duration, settings.Samples),
+	}
+}
+
+func (l *latencies) add(latency time.Duration) {
+	if l.settings == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.samples[l.index] = latency
+	l.index = (l.index + 1) % len(l.samples)
+
+	if l.count < len(l.samples) {
+		l.count++
+	}
+}
+
+func (l *latencies) percentile() time.Duration {
+	l.mutex.Lock()
+
+	if l.count < minHedgeSamples {
+		l.mutex.Unlock()
+		return l.settings.Delay
+	}
+
+	samples := make([]time.Duration, l.count)
+	copy(samples, l.samples[:l.count])
+
+	l.mutex.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+
+	delay := samples[int(float64(len(samples)-1)*l.settings.Percentile)]
+	if delay < l.settings.Delay {
+		return l.settings.Delay
+	}
+
+	return delay
+}
@@ -0,0 +1,190 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Records which gateways were attempted, in order.
+type attempts struct {
+	mutex sync.Mutex
+	urls  []string
+}
+
+func (a *attempts) add(url string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.urls = append(a.urls, url)
+}
+
+func (a *attempts) get() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return append([]string(nil), a.urls...)
+}
+
+func TestHedgeSlowGateway(t *testing.T) {
+	c := newControlPlane(t, "https://slow", "https://fast")
+	e := c.executor(t, &ExecutorSettings{
+		Hedge: &HedgeSettings{
+			Delay: time.Millisecond * 20,
+		},
+	})
+
+	cancelled := make(chan struct{})
+	value, err := e.Hedge(context.Background(), func(ctx context.Context, url string) (interface{}, error) {
+		if url == "https://slow" {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		}
+
+		return url, nil
+	})
+
+	if err != nil || value != "https://fast" {
+		t.Fatalf("expected the hedged response, got %v and %v", value, err)
+	}
+
+	// The losing request is cancelled.
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the slow request to be cancelled")
+	}
+}
+
+func TestHedgeFastFailure(t *testing.T) {
+	c := newControlPlane(t, "https://failing", "https://working")
+	e := c.executor(t, &ExecutorSettings{
+		Hedge: &HedgeSettings{
+			Delay: time.Hour,
+		},
+	})
+
+	// The next gateway is tried right away rather than after the delay.
+	value, err := e.Hedge(context.Background(), func(ctx context.Context, url string) (interface{}, error) {
+		if url == "https://failing" {
+			return nil, unavailableError
+		}
+
+		return url, nil
+	})
+
+	if err != nil || value != "https://working" {
+		t.Fatalf("expected the response of the next gateway, got %v and %v", value, err)
+	}
+}
+
+func TestHedgeRetries(t *testing.T) {
+	c := newControlPlane(t, "https://a", "https://b", "https://c", "https://d")
+	e := c.executor(t, &ExecutorSettings{
+		MaxRetries: 3,
+		Hedge: &HedgeSettings{
+			Delay: time.Hour,
+		},
+	})
+
+	var a attempts
+	_, err := e.Hedge(context.Background(), func(ctx context.Context, url string) (interface{}, error) {
+		a.add(url)
+		return nil, unavailableError
+	})
+
+	if err != unavailableError {
+		t.Fatalf("expected the gateway error, got %v", err)
+	}
+
+	// Both hedged attempts count towards MaxRetries, so only one retry
+	// is left, which goes to a gateway that hasn't failed yet.
+	urls := a.get()
+	if len(urls) != 3 {
+		t.Fatalf("expected 3 attempts, got %v", urls)
+	}
+
+	if urls[2] != "https://c" {
+		t.Errorf("expected the retry to go to the next gateway, got %v", urls)
+	}
+}
+
+func TestHedgeNonRetryable(t *testing.T) {
+	c := newControlPlane(t, "https://a", "https://b")
+	e := c.executor(t, &ExecutorSettings{
+		Hedge: &HedgeSettings{
+			Delay: time.Hour,
+		},
+	})
+
+	var a attempts
+	_, err := e.Hedge(context.Background(), func(ctx context.Context, url string) (interface{}, error) {
+		a.add(url)
+		return nil, discoveryFailedError
+	})
+
+	if err != discoveryFailedError {
+		t.Fatalf("expected the request error, got %v", err)
+	}
+
+	if urls := a.get(); len(urls) != 1 {
+		t.Errorf("expected a single attempt, got %v", urls)
+	}
+}
+
+func TestHedgeDisabled(t *testing.T) {
+	c := newControlPlane(t, "https://slow", "https://fast")
+	e := c.executor(t, &ExecutorSettings{})
+
+	var a attempts
+	value, err := e.Hedge(context.Background(), func(ctx context.Context, url string) (interface{}, error) {
+		a.add(url)
+		time.Sleep(time.Millisecond * 20)
+		return url, nil
+	})
+
+	if err != nil || value != "https://slow" {
+		t.Fatalf("expected the response of the current gateway, got %v and %v", value, err)
+	}
+
+	if urls := a.get(); len(urls) != 1 {
+		t.Errorf("expected a single attempt, got %v", urls)
+	}
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	l := newLatencies(&HedgeSettings{
+		Percentile: 0.9,
+		Delay:      time.Millisecond * 5,
+		Samples:    20,
+	})
+
+	// The delay is used until enough latencies have been observed.
+	for n := 1; n < minHedgeSamples; n++ {
+		l.add(time.Millisecond * 100)
+	}
+
+	if delay := l.percentile(); delay != time.Millisecond*5 {
+		t.Errorf("expected the delay, got %v", delay)
+	}
+
+	for n := 1; n <= 20; n++ {
+		l.add(time.Millisecond * time.Duration(n))
+	}
+
+	// Only the most recent latencies are kept.
+	if delay := l.percentile(); delay != time.Millisecond*18 {
+		t.Errorf("expected the percentile, got %v", delay)
+	}
+
+	for n := 0; n < 20; n++ {
+		l.add(time.Millisecond)
+	}
+
+	// The delay is the lower bound.
+	if delay := l.percentile(); delay != time.Millisecond*5 {
+		t.Errorf("expected the delay as the lower bound, got %v", delay)
+	}
+}
@@ -0,0 +1,119 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/backoff"
+	rerrors "github.com/styrainc/styra-run-sdk-go/internal/errors"
+	"github.com/styrainc/styra-run-sdk-go/internal/utils"
+)
+
+const (
+	DefaultInitBackoffBase = time.Second
+	DefaultInitBackoffMax  = time.Second * 30
+)
+
+// An initializer guards the initial discovery of the gateway list. Only one
+// discovery is in flight at a time and concurrent callers wait for its
+// outcome. Once it succeeded, it never runs again. A failure is cached
+// until the backoff policy allows another attempt, so callers fail fast
+// rather than stampeding the discovery endpoint.
+type initializer struct {
+	backoff  backoff.Policy
+	mutex    sync.Mutex
+	done     bool
+	pending  chan struct{}
+	err      error
+	failures int
+	delay    time.Duration
+	retryAt  time.Time
+}
+
+func newInitializer(policy backoff.Policy) *initializer {
+	if policy == nil {
+		policy = backoff.Exponential(DefaultInitBackoffBase, DefaultInitBackoffMax)
+	}
+
+	return &initializer{
+		backoff: policy,
+	}
+}
+
+// Do runs fn unless it already succeeded. If force is set, a cached
+// failure is ignored and fn runs right away.
+func (i *initializer) Do(ctx context.Context, force bool, fn func(ctx context.Context) error) error {
+	for {
+		i.mutex.Lock()
+
+		if i.done {
+			i.mutex.Unlock()
+			return nil
+		}
+
+		if pending := i.pending; pending != nil {
+			i.mutex.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-pending:
+				// If the leader gave up, its context is done rather than ours,
+				// so either its outcome is cached or we take over.
+				continue
+			}
+		}
+
+		if i.err != nil && !force && time.Now().Before(i.retryAt) {
+			err := i.err
+			i.mutex.Unlock()
+			return err
+		}
+
+		pending := make(chan struct{})
+		i.pending = pending
+		i.mutex.Unlock()
+
+		err := fn(ctx)
+
+		i.mutex.Lock()
+		i.settle(ctx, err)
+		i.pending = nil
+		close(pending)
+		i.mutex.Unlock()
+
+		return err
+	}
+}
+
+// Record the outcome of an attempt. Must be called with the mutex held.
+func (i *initializer) settle(ctx context.Context, err error) {
+	if err == nil {
+		i.done, i.err = true, nil
+		return
+	}
+
+	if utils.ContextDone(ctx) {
+		return
+	}
+
+	i.failures++
+	i.delay = i.backoff.Delay(i.failures, i.delay)
+
+	if httpError, ok := err.(rerrors.HttpError); ok && httpError.RetryAfter() > i.delay {
+		i.delay = httpError.RetryAfter()
+	}
+
+	i.err = err
+	i.retryAt = time.Now().Add(i.delay)
+}
+
+// Ready reports whether discovery succeeded, and otherwise the last
+// failure, if any.
+func (i *initializer) Ready() (bool, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	return i.done, i.err
+}
@@ -0,0 +1,147 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/backoff"
+)
+
+var discoveryFailedError = errors.New("discovery failed")
+
+func TestInitializerSucceedsOnce(t *testing.T) {
+	i := newInitializer(backoff.Constant(time.Hour))
+
+	var calls int32
+	fn := func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}
+
+	for n := 0; n < 3; n++ {
+		if err := i.Do(context.Background(), n == 2, fn); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+
+	if done, err := i.Ready(); !done || err != nil {
+		t.Errorf("expected to be ready, got %v and %v", done, err)
+	}
+}
+
+func TestInitializerBackoff(t *testing.T) {
+	i := newInitializer(backoff.Constant(time.Millisecond * 50))
+
+	var calls int32
+	fn := func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return discoveryFailedError
+	}
+
+	if err := i.Do(context.Background(), false, fn); err != discoveryFailedError {
+		t.Fatalf("expected the discovery error, got %v", err)
+	}
+
+	// The failure is cached until the backoff allows another attempt.
+	if err := i.Do(context.Background(), false, fn); err != discoveryFailedError {
+		t.Fatalf("expected the cached discovery error, got %v", err)
+	}
+
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("expected 1 call while backing off, got %d", calls)
+	}
+
+	if done, err := i.Ready(); done || err != discoveryFailedError {
+		t.Errorf("expected the discovery error, got %v and %v", done, err)
+	}
+
+	// Forcing ignores the cached failure.
+	i.Do(context.Background(), true, fn)
+
+	if calls := atomic.LoadInt32(&calls); calls != 2 {
+		t.Errorf("expected 2 calls after forcing, got %d", calls)
+	}
+
+	time.Sleep(time.Millisecond * 60)
+
+	i.Do(context.Background(), false, fn)
+
+	if calls := atomic.LoadInt32(&calls); calls != 3 {
+		t.Errorf("expected 3 calls after backing off, got %d", calls)
+	}
+}
+
+func TestInitializerLeaderCancel(t *testing.T) {
+	i := newInitializer(backoff.Constant(time.Hour))
+
+	started := make(chan struct{})
+	leader, cancel := context.WithCancel(context.Background())
+
+	leaderErr := make(chan error, 1)
+	go func() {
+		leaderErr <- i.Do(leader, false, func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+
+	<-started
+
+	var calls int32
+	followerErr := make(chan error, 1)
+	go func() {
+		followerErr <- i.Do(context.Background(), false, func(ctx context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		})
+	}()
+
+	// Give the follower a chance to wait for the leader.
+	time.Sleep(time.Millisecond * 50)
+	cancel()
+
+	if err := <-leaderErr; err != context.Canceled {
+		t.Errorf("expected the leader to be cancelled, got %v", err)
+	}
+
+	// The leader giving up isn't a discovery failure, so the follower
+	// takes over right away rather than backing off.
+	if err := <-followerErr; err != nil {
+		t.Errorf("expected the follower to succeed, got %v", err)
+	}
+
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("expected the follower to take over, got %d calls", calls)
+	}
+}
+
+func TestInitializerFollowerCancel(t *testing.T) {
+	i := newInitializer(nil)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	go i.Do(context.Background(), false, func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	if err := i.Do(ctx, false, nil); err != context.DeadlineExceeded {
+		t.Errorf("expected the follower to give up, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package discovery
+
+import (
+	"context"
+	"time"
+
+	rerrors "github.com/styrainc/styra-run-sdk-go/internal/errors"
+)
+
+// An AttemptEvent describes a single request against a gateway.
+type AttemptEvent struct {
+	// The gateway url the request was sent to.
+	Gateway string
+
+	// The attempt number, starting at 0 for the first attempt.
+	Attempt int
+
+	// Whether the request was sent as a hedge.
+	Hedged bool
+
+	// How long the request took.
+	Duration time.Duration
+
+	// The http status code for http errors, or zero otherwise.
+	Code int
+
+	// The error, if the request failed.
+	Err error
+
+	// Whether the error caused a failover to another gateway.
+	Retryable bool
+}
+
+// An Observer is notified of executor events. Every callback is optional
+// and is called synchronously, so callbacks should return quickly.
+type Observer struct {
+	// Called after every request against a gateway.
+	OnAttempt func(ctx context.Context, event *AttemptEvent)
+
+	// Called when the executor moves on from a gateway after a failure.
+	OnFailover func(ctx context.Context, from, to string)
+
+	// Called after every attempt to discover the gateway list.
+	OnDiscovery func(ctx context.Context, gateways []*Gateway, err error)
+}
+
+func (e *executor) notifyAttempt(ctx context.Context, event *AttemptEvent) {
+	if httpError, ok := event.Err.(rerrors.HttpError); ok {
+		event.Code = httpError.Code()
+	}
+
+	if event.Retryable {
+		e.logger.WarnContext(
+			ctx,
+			"styra run request failed",
+			"gateway", event.Gateway,
+			"attempt", event.Attempt,
+			"hedged", event.Hedged,
+			"error", event.Err,
+		)
+	}
+
+	for _, observer := range e.settings.Observers {
+		if observer.OnAttempt != nil {
+			observer.OnAttempt(ctx, event)
+		}
+	}
+}
+
+func (e *executor) notifyFailover(ctx context.Context, from, to string) {
+	e.logger.InfoContext(ctx, "styra run gateway switched", "from", from, "to", to)
+
+	for _, observer := range e.settings.Observers {
+		if observer.OnFailover != nil {
+			observer.OnFailover(ctx, from, to)
+		}
+	}
+}
+
+func (e *executor) notifyDiscovery(ctx context.Context, gateways []*Gateway, err error) {
+	if err != nil {
+		e.logger.ErrorContext(ctx, "styra run gateway discovery failed", "url", e.settings.Url, "error", err)
+	} else {
+		e.logger.DebugContext(ctx, "styra run gateways discovered", "url", e.settings.Url, "count", len(gateways))
+	}
+
+	for _, observer := range e.settings.Observers {
+		if observer.OnDiscovery != nil {
+			observer.OnDiscovery(ctx, gateways, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package discovery
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+
+	rerrors "github.com/styrainc/styra-run-sdk-go/internal/errors"
+)
+
+// Retryable decides whether a failed request should be retried on
+// the next gateway.
+type Retryable func(err error) bool
+
+// DefaultRetryable retries on bad gateway status codes and on transport level
+// failures, such as refused connections, dns failures, tls handshake errors
+// and timeouts. Optionally, it also retries when the data plane responds with
+// 429 too many requests.
+func DefaultRetryable(tooManyRequests bool) Retryable {
+	return func(err error) bool {
+		if httpError, ok := err.(rerrors.HttpError); ok {
+			if badGatewayCodes[httpError.Code()] {
+				return true
+			}
+
+			return tooManyRequests && httpError.Code() == http.StatusTooManyRequests
+		}
+
+		return isTransportError(err)
+	}
+}
+
+func isTransportError(err error) bool {
+	// The http client wraps every failure to send a request
+	// or receive a response in a url error.
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		return true
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) {
+		return true
+	}
+
+	// The connection can also fail while reading the response body.
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
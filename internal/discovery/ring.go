@@ -0,0 +1,91 @@
+package discovery
+
+import "sync"
+
+// A ring is an ordered list of gateway urls with a cursor
+// that wraps around. It's safe for concurrent use so that
+// strategies can reorder it in the background. Gateways that
+// the breaker considers unavailable are skipped, unless all
+// of them are, in which case the cursor is used as is.
+type ring struct {
+	urls    []string
+	index   int
+	breaker Breaker
+	mutex   sync.RWMutex
+}
+
+func newRing(breaker Breaker) ring {
+	if breaker == nil {
+		breaker = NewBreaker(nil)
+	}
+
+	return ring{
+		breaker: breaker,
+	}
+}
+
+func (r *ring) set(urls []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.urls = urls
+	r.index = 0
+}
+
+// Advance past the gateway that's currently chosen,
+// which isn't necessarily the one under the cursor.
+func (r *ring) next() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.urls) == 0 {
+		return
+	}
+
+	r.index = (r.choose() + 1) % len(r.urls)
+}
+
+func (r *ring) gateway() string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if len(r.urls) == 0 {
+		return ""
+	}
+
+	return r.urls[r.choose()]
+}
+
+// All gateways in order, starting with the one that's currently chosen.
+func (r *ring) gateways() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]string, 0, len(r.urls))
+	for i, start := 0, r.choose(); i < len(r.urls); i++ {
+		result = append(result, r.urls[(start+i)%len(r.urls)])
+	}
+
+	return result
+}
+
+func (r *ring) choose() int {
+	for i := 0; i < len(r.urls); i++ {
+		index := (r.index + i) % len(r.urls)
+
+		if r.breaker.Available(r.urls[index]) {
+			return index
+		}
+	}
+
+	return r.index
+}
+
+func urls(gateways []*Gateway) []string {
+	result := make([]string, 0, len(gateways))
+	for _, gateway := range gateways {
+		result = append(result, gateway.Url)
+	}
+
+	return result
+}
@@ -3,28 +3,32 @@ package discovery
 import "context"
 
 type simple struct {
-	gateways []*Gateway
-	index    int
+	ring ring
 }
 
-func NewSimpleStrategy() Strategy {
-	return &simple{}
+func NewSimpleStrategy(breaker Breaker) Strategy {
+	return &simple{
+		ring: newRing(breaker),
+	}
 }
 
 func (s *simple) Init(ctx context.Context, gateways []*Gateway) error {
-	s.gateways = gateways
+	s.ring.set(urls(gateways))
 
 	return nil
 }
 
 func (s *simple) Next() {
-	s.index++
-
-	if s.index == len(s.gateways) {
-		s.index = 0
-	}
+	s.ring.next()
 }
 
 func (s *simple) Gateway() string {
-	return s.gateways[s.index].Url
+	return s.ring.gateway()
+}
+
+func (s *simple) Gateways() []string {
+	return s.ring.gateways()
+}
+
+func (s *simple) Close() {
 }
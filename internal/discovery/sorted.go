@@ -0,0 +1,167 @@
+package discovery
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/rest"
+)
+
+const (
+	DefaultProbeInterval = time.Minute * 5
+	DefaultProbeTimeout  = time.Second * 5
+)
+
+type SortedSettings struct {
+	// How often gateways are re-ranked in the background.
+	Interval time.Duration
+
+	// How long to wait for a single gateway to respond.
+	Timeout time.Duration
+
+	// The http client used to probe gateways.
+	Client *http.Client
+
+	// Optional breaker used to skip unavailable gateways.
+	Breaker Breaker
+
+	// Optional interceptors for probe requests.
+	Interceptors []rest.Interceptor
+}
+
+type sorted struct {
+	settings *SortedSettings
+	gateways []*Gateway
+	ring     ring
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewSortedStrategy ranks gateways by their measured round trip latency,
+// starting with the fastest one. In the event of a failure, the next
+// fastest gateway is tried in a round robin fashion. Gateways are used in
+// discovery order until the first ranking, which runs in the background,
+// completes, and are then periodically re-ranked until the strategy is
+// closed.
+func NewSortedStrategy(settings *SortedSettings) Strategy {
+	if settings.Interval <= 0 {
+		settings.Interval = DefaultProbeInterval
+	}
+
+	if settings.Timeout <= 0 {
+		settings.Timeout = DefaultProbeTimeout
+	}
+
+	return &sorted{
+		settings: settings,
+		ring:     newRing(settings.Breaker),
+		done:     make(chan struct{}),
+	}
+}
+
+// Init doesn't wait for the gateways to be probed, since it's called with
+// the context of whichever request happened to trigger discovery.
+func (s *sorted) Init(ctx context.Context, gateways []*Gateway) error {
+	s.gateways = gateways
+	s.ring.set(urls(gateways))
+
+	go s.loop()
+
+	return nil
+}
+
+func (s *sorted) Next() {
+	s.ring.next()
+}
+
+func (s *sorted) Gateway() string {
+	return s.ring.gateway()
+}
+
+func (s *sorted) Gateways() []string {
+	return s.ring.gateways()
+}
+
+func (s *sorted) Close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *sorted) loop() {
+	s.rank(context.Background())
+
+	ticker := time.NewTicker(s.settings.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.rank(context.Background())
+		}
+	}
+}
+
+// Probe every gateway concurrently and reorder the ring by latency. Gateways
+// that could not be reached are moved to the end, keeping their original order.
+func (s *sorted) rank(ctx context.Context) {
+	latencies := make([]time.Duration, len(s.gateways))
+
+	var wg sync.WaitGroup
+	for i, gateway := range s.gateways {
+		wg.Add(1)
+
+		go func(i int, url string) {
+			defer wg.Done()
+
+			latencies[i] = s.probe(ctx, url)
+		}(i, gateway.Url)
+	}
+
+	wg.Wait()
+
+	indices := make([]int, len(s.gateways))
+	for i := range indices {
+		indices[i] = i
+	}
+
+	sort.SliceStable(indices, func(i, j int) bool {
+		return latencies[indices[i]] < latencies[indices[j]]
+	})
+
+	result := make([]string, 0, len(indices))
+	for _, i := range indices {
+		result = append(result, s.gateways[i].Url)
+	}
+
+	s.ring.set(result)
+}
+
+// Any http response counts as reachable since we're only interested
+// in the round trip time, not whether the gateway accepts the request.
+func (s *sorted) probe(ctx context.Context, url string) time.Duration {
+	ctx, cancel := context.WithTimeout(ctx, s.settings.Timeout)
+	defer cancel()
+
+	rest := &rest.Rest{
+		Url:          url,
+		Method:       http.MethodGet,
+		Client:       s.settings.Client,
+		Operation:    OperationProbe,
+		Gateway:      url,
+		Interceptors: s.settings.Interceptors,
+	}
+
+	start := time.Now()
+	if err := rest.Execute(ctx); err != nil {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Since(start)
+}
@@ -0,0 +1,96 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newProbedGateway(t *testing.T, delay time.Duration) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(delay):
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func waitForGateways(t *testing.T, s Strategy, expected []string) {
+	deadline := time.Now().Add(time.Second * 5)
+	for !reflect.DeepEqual(s.Gateways(), expected) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected gateways %v, got %v", expected, s.Gateways())
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestSortedRanksByLatency(t *testing.T) {
+	slow := newProbedGateway(t, time.Millisecond*200)
+	fast := newProbedGateway(t, 0)
+
+	s := NewSortedStrategy(&SortedSettings{
+		Interval: time.Hour,
+	})
+	defer s.Close()
+
+	start := time.Now()
+	if err := s.Init(context.Background(), []*Gateway{{Url: slow}, {Url: fast}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Init doesn't wait for the probes, so discovery order is used until
+	// the first ranking completes.
+	if elapsed := time.Since(start); elapsed >= time.Millisecond*200 {
+		t.Errorf("expected Init not to wait for the probes, took %v", elapsed)
+	}
+
+	if gateways := s.Gateways(); !reflect.DeepEqual(gateways, []string{slow, fast}) {
+		t.Errorf("expected discovery order before ranking, got %v", gateways)
+	}
+
+	waitForGateways(t, s, []string{fast, slow})
+
+	if gateway := s.Gateway(); gateway != fast {
+		t.Errorf("expected the fastest gateway, got %s", gateway)
+	}
+
+	s.Next()
+
+	if gateway := s.Gateway(); gateway != slow {
+		t.Errorf("expected the next fastest gateway, got %s", gateway)
+	}
+}
+
+func TestSortedUnreachableLast(t *testing.T) {
+	reachable := newProbedGateway(t, time.Millisecond*20)
+
+	// Nothing listens on this gateway anymore.
+	server := httptest.NewServer(http.NotFoundHandler())
+	closed := server.URL
+	server.Close()
+
+	s := NewSortedStrategy(&SortedSettings{
+		Interval: time.Hour,
+		Timeout:  time.Millisecond * 50,
+	})
+	defer s.Close()
+
+	// A gateway that doesn't respond in time is unreachable, too. Both keep
+	// their discovery order at the end.
+	timeout := newProbedGateway(t, time.Second)
+
+	if err := s.Init(context.Background(), []*Gateway{{Url: closed}, {Url: timeout}, {Url: reachable}}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForGateways(t, s, []string{reachable, closed, timeout})
+}
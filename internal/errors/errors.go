@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/styrainc/styra-run-sdk-go/internal/rest"
 )
@@ -20,12 +22,14 @@ type HttpError interface {
 
 	Code() int
 	Details() *ErrorResponse
+	RetryAfter() time.Duration
 }
 
 type httpError struct {
-	code    int
-	details *ErrorResponse
-	message string
+	code       int
+	details    *ErrorResponse
+	message    string
+	retryAfter time.Duration
 }
 
 func NewHttpError(code int, details *ErrorResponse) error {
@@ -60,30 +64,65 @@ func (h *httpError) Details() *ErrorResponse {
 	return h.details
 }
 
+// RetryAfter is the delay requested by the server through
+// the `Retry-After` header, or zero if it wasn't set.
+func (h *httpError) RetryAfter() time.Duration {
+	return h.retryAfter
+}
+
 func (h *httpError) Error() string {
 	return h.message
 }
 
 func HttpErrorDecoder(value interface{}) rest.Decoder {
-	return func(code int, bytes []byte) error {
+	return func(code int, header http.Header, bytes []byte) error {
 		if code >= http.StatusOK && code <= http.StatusIMUsed {
 			if err := json.Unmarshal(bytes, value); err != nil {
 				return err
 			}
 		} else {
+			// Load balancers and proxies in front of the data plane don't
+			// necessarily respond with json, but the status code still
+			// matters to callers, so don't mask it with a decoding error.
 			details := &ErrorResponse{}
 
 			if err := json.Unmarshal(bytes, details); err != nil {
-				return err
+				details = nil
 			}
 
-			return NewHttpError(code, details)
+			err := NewHttpError(code, details).(*httpError)
+			err.retryAfter = parseRetryAfter(header.Get("Retry-After"))
+
+			return err
 		}
 
 		return nil
 	}
 }
 
+// The header is either a number of seconds or an http date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+
+		return 0
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
 func IsHttpError(err error, code int) bool {
 	httpError, ok := err.(HttpError)
 	return ok && httpError.Code() == code
@@ -103,3 +142,24 @@ func NewAuthzError() error {
 func (a *authzError) Error() string {
 	return "forbidden"
 }
+
+// UndefinedResultError is returned by Check in strict mode
+// if the policy result, or the selected part of it, is undefined.
+type UndefinedResultError struct {
+	Path string
+}
+
+func (u *UndefinedResultError) Error() string {
+	return fmt.Sprintf("result for %s is undefined", u.Path)
+}
+
+// NonBooleanResultError is returned by Check in strict mode if the
+// policy result, or the selected part of it, isn't a boolean.
+type NonBooleanResultError struct {
+	Path   string
+	Result interface{}
+}
+
+func (n *NonBooleanResultError) Error() string {
+	return fmt.Sprintf("result for %s is not a boolean: %T", n.Path, n.Result)
+}
@@ -0,0 +1,71 @@
+package flight
+
+import (
+	"context"
+	"sync"
+)
+
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// A Group collapses concurrent calls with the same key into a single
+// call whose result is shared with every caller.
+type Group struct {
+	calls map[string]*call
+	mutex sync.Mutex
+}
+
+// Do runs fn unless a call with the same key is already in flight, in which
+// case it waits for that call's result instead. Fn runs with the context of
+// the caller that started it. If that caller gives up, callers that are still
+// waiting don't inherit its context error, they try again instead.
+func (g *Group) Do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	for {
+		g.mutex.Lock()
+
+		if g.calls == nil {
+			g.calls = make(map[string]*call)
+		}
+
+		if c, ok := g.calls[key]; ok {
+			g.mutex.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-c.done:
+			}
+
+			if c.err != nil && (c.err == context.Canceled || c.err == context.DeadlineExceeded) && ctx.Err() == nil {
+				continue
+			}
+
+			return c.value, c.err
+		}
+
+		c := &call{
+			done: make(chan struct{}),
+		}
+
+		g.calls[key] = c
+		g.mutex.Unlock()
+
+		c.value, c.err = fn(ctx)
+
+		// A context error means the leader gave up, not that the call failed.
+		if ctx.Err() != nil && c.err != nil {
+			c.err = ctx.Err()
+		}
+
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+
+		close(c.done)
+
+		return c.value, c.err
+	}
+}
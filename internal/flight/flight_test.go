@@ -0,0 +1,119 @@
+package flight
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoShares(t *testing.T) {
+	var group Group
+	var calls int32
+
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "value", nil
+	}
+
+	results := make(chan interface{}, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			value, _ := group.Do(context.Background(), "key", fn)
+			results <- value
+		}()
+	}
+
+	// Give both callers a chance to join before the call finishes.
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if value := <-results; value != "value" {
+			t.Errorf("expected value, got %v", value)
+		}
+	}
+
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestDoLeaderCancel(t *testing.T) {
+	var group Group
+	var calls int32
+
+	started := make(chan struct{})
+	leader, cancel := context.WithCancel(context.Background())
+
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := group.Do(leader, "key", func(ctx context.Context) (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+		leaderErr <- err
+	}()
+
+	<-started
+
+	type result struct {
+		value interface{}
+		err   error
+	}
+
+	follower := make(chan result, 1)
+	go func() {
+		value, err := group.Do(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return "value", nil
+		})
+
+		follower <- result{value, err}
+	}()
+
+	// Give the follower a chance to join the leader's call.
+	time.Sleep(time.Millisecond * 50)
+	cancel()
+
+	if err := <-leaderErr; err != context.Canceled {
+		t.Errorf("expected the leader to be cancelled, got %v", err)
+	}
+
+	// The follower must not inherit the leader's context error.
+	if r := <-follower; r.err != nil || r.value != "value" {
+		t.Errorf("expected the follower to get value, got %v and %v", r.value, r.err)
+	}
+
+	if calls := atomic.LoadInt32(&calls); calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestDoFollowerCancel(t *testing.T) {
+	var group Group
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	go group.Do(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		return "value", nil
+	})
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	if _, err := group.Do(ctx, "key", nil); err != context.DeadlineExceeded {
+		t.Errorf("expected the follower to give up, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	redacted = "[REDACTED]"
+)
+
+// OrDiscard returns the logger, or one that discards everything if it's nil.
+func OrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.New(discardHandler{})
+	}
+
+	return logger
+}
+
+// Sensitive emits an attribute whose value is redacted unless reveal is set.
+// Inputs and data may contain personal information, so they're redacted
+// by default.
+func Sensitive(key string, value interface{}, reveal bool) slog.Attr {
+	if !reveal {
+		return slog.String(key, redacted)
+	}
+
+	return slog.Any(key, value)
+}
+
+type discardHandler struct {
+}
+
+func (d discardHandler) Enabled(context.Context, slog.Level) bool {
+	return false
+}
+
+func (d discardHandler) Handle(context.Context, slog.Record) error {
+	return nil
+}
+
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return d
+}
+
+func (d discardHandler) WithGroup(string) slog.Handler {
+	return d
+}
+
+// A responseWriter captures the status code and the
+// cause of an error response for logging purposes.
+type responseWriter struct {
+	http.ResponseWriter
+
+	status int
+	cause  error
+}
+
+func (r *responseWriter) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseWriter) RecordError(err error) {
+	r.cause = err
+}
+
+// Handler logs requests that a proxy rejected or failed to serve: client
+// errors at info level and server errors at error level. Request bodies
+// aren't logged. If the logger is nil, the handler is returned as is.
+func Handler(logger *slog.Logger, proxy string, handler http.HandlerFunc) http.HandlerFunc {
+	if logger == nil {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := &responseWriter{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+		}
+
+		handler(writer, r)
+
+		if writer.status < http.StatusBadRequest {
+			return
+		}
+
+		level := slog.LevelInfo
+		if writer.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		attributes := []slog.Attr{
+			slog.String("proxy", proxy),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", writer.status),
+		}
+
+		if writer.cause != nil {
+			attributes = append(attributes, slog.String("error", writer.cause.Error()))
+		}
+
+		logger.LogAttrs(r.Context(), level, "styra run proxy request rejected", attributes...)
+	}
+}
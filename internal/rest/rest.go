@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
@@ -14,9 +15,13 @@ const (
 	DefaultTimeout = time.Second * 30
 )
 
+var (
+	noResponseError = errors.New("no response")
+)
+
 type (
 	Encoder func() ([]byte, error)
-	Decoder func(code int, bytes []byte) error
+	Decoder func(code int, header http.Header, bytes []byte) error
 )
 
 func JsonEncoder(value interface{}) Encoder {
@@ -26,20 +31,52 @@ func JsonEncoder(value interface{}) Encoder {
 }
 
 func JsonDecoder(value interface{}) Decoder {
-	return func(code int, bytes []byte) error {
+	return func(code int, header http.Header, bytes []byte) error {
 		return json.Unmarshal(bytes, value)
 	}
 }
 
+// A Call describes an outgoing request as seen by interceptors.
+type Call struct {
+	// The name of the operation, e.g. `query`.
+	Operation string
+
+	// The base url the request is sent to, i.e. the data plane url
+	// for data plane requests, or the environment url for discovery.
+	Gateway string
+
+	// The data or policy path the operation refers to, if any.
+	Path string
+
+	// The input or data sent with the operation, if any.
+	Input interface{}
+
+	// The http request. Interceptors may modify it, or replace it
+	// with a new one, before passing the call on.
+	Request *http.Request
+}
+
+// An Invoker sends the request of a call and emits the response.
+type Invoker func(call *Call) (*http.Response, error)
+
+// An Interceptor wraps the sending of a request. It must call next to pass
+// the call on, and sees the response and error that are emitted by it.
+type Interceptor func(call *Call, next Invoker) (*http.Response, error)
+
 type Rest struct {
-	Url     string
-	Method  string
-	Client  *http.Client
-	Headers map[string]string
-	Queries map[string]string
-	Encoder Encoder
-	Decoder Decoder
-	Code    int
+	Url          string
+	Method       string
+	Client       *http.Client
+	Headers      map[string]string
+	Queries      map[string]string
+	Encoder      Encoder
+	Decoder      Decoder
+	Code         int
+	Operation    string
+	Gateway      string
+	Path         string
+	Input        interface{}
+	Interceptors []Interceptor
 }
 
 func (r *Rest) Execute(ctx context.Context) error {
@@ -88,10 +125,33 @@ func (r *Rest) Execute(ctx context.Context) error {
 		}
 	}
 
-	// Make the request.
-	httpResponse, err := client.Do(httpRequest)
+	// Make the request, passing it through any interceptors. The
+	// first interceptor is the outermost one.
+	invoker := func(call *Call) (*http.Response, error) {
+		return client.Do(call.Request)
+	}
+
+	for i := len(r.Interceptors) - 1; i >= 0; i-- {
+		interceptor, next := r.Interceptors[i], invoker
+
+		invoker = func(call *Call) (*http.Response, error) {
+			return interceptor(call, next)
+		}
+	}
+
+	call := &Call{
+		Operation: r.Operation,
+		Gateway:   r.Gateway,
+		Path:      r.Path,
+		Input:     r.Input,
+		Request:   httpRequest,
+	}
+
+	httpResponse, err := invoker(call)
 	if err != nil {
 		return err
+	} else if httpResponse == nil {
+		return noResponseError
 	}
 
 	defer httpResponse.Body.Close()
@@ -106,7 +166,7 @@ func (r *Rest) Execute(ctx context.Context) error {
 
 	// Decode the response body.
 	if r.Decoder != nil {
-		if err := r.Decoder(r.Code, body); err != nil {
+		if err := r.Decoder(r.Code, httpResponse.Header, body); err != nil {
 			return err
 		}
 	}
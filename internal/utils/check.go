@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/errors"
+)
+
+// CheckResult turns the policy result for path into a decision. If pointer
+// is set, it selects the boolean within the result. Anything other than true
+// is a denial, unless strict is set, in which case an undefined result is an
+// *errors.UndefinedResultError and a non boolean one an
+// *errors.NonBooleanResultError.
+func CheckResult(path string, result interface{}, defined bool, pointer string, strict bool) (bool, error) {
+	if pointer != "" && defined {
+		if result, defined = ResolvePointer(result, pointer); !defined {
+			result = nil
+		}
+	}
+
+	value, ok := result.(bool)
+
+	if strict {
+		if !defined {
+			return false, &errors.UndefinedResultError{
+				Path: path,
+			}
+		} else if !ok {
+			return false, &errors.NonBooleanResultError{
+				Path:   path,
+				Result: result,
+			}
+		}
+	}
+
+	return ok && value, nil
+}
+
+// ResolvePointer resolves a json pointer, as described in rfc 6901, against
+// a decoded json document. Returns false if the pointer doesn't point
+// anywhere.
+func ResolvePointer(document interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return document, true
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+
+	replacer := strings.NewReplacer("~1", "/", "~0", "~")
+
+	current := document
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = replacer.Replace(token)
+
+		switch value := current.(type) {
+		case map[string]interface{}:
+			next, ok := value[token]
+			if !ok {
+				return nil, false
+			}
+
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false
+			}
+
+			current = value[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/styrainc/styra-run-sdk-go/internal/errors"
+)
+
+func TestResolvePointer(t *testing.T) {
+	var document interface{}
+	json.Unmarshal([]byte(`{"allow": true, "a/b": {"m~n": 1}, "list": [false, {"x": "y"}]}`), &document)
+
+	for _, test := range []struct {
+		pointer string
+		value   interface{}
+		ok      bool
+	}{
+		{"", document, true},
+		{"/allow", true, true},
+		{"/a~1b/m~0n", float64(1), true},
+		{"/list/0", false, true},
+		{"/list/1/x", "y", true},
+		{"/list/2", nil, false},
+		{"/list/-1", nil, false},
+		{"/list/x", nil, false},
+		{"/missing", nil, false},
+		{"/allow/nested", nil, false},
+		{"allow", nil, false},
+	} {
+		value, ok := ResolvePointer(document, test.pointer)
+		if ok != test.ok || !reflect.DeepEqual(value, test.value) {
+			t.Errorf("%q: expected %v and %v, got %v and %v", test.pointer, test.value, test.ok, value, ok)
+		}
+	}
+}
+
+func TestCheckResult(t *testing.T) {
+	object := map[string]interface{}{
+		"allow":  true,
+		"reason": "ok",
+	}
+
+	for _, test := range []struct {
+		name    string
+		result  interface{}
+		defined bool
+		pointer string
+		allowed bool
+		err     interface{}
+	}{
+		{"true", true, true, "", true, nil},
+		{"false", false, true, "", false, nil},
+		{"undefined", nil, false, "", false, &errors.UndefinedResultError{}},
+		{"null", nil, true, "", false, &errors.NonBooleanResultError{}},
+		{"object", object, true, "", false, &errors.NonBooleanResultError{}},
+		{"pointer", object, true, "/allow", true, nil},
+		{"pointer to a string", object, true, "/reason", false, &errors.NonBooleanResultError{}},
+		{"pointer to nothing", object, true, "/missing", false, &errors.UndefinedResultError{}},
+	} {
+		allowed, err := CheckResult("path", test.result, test.defined, test.pointer, false)
+		if allowed != test.allowed || err != nil {
+			t.Errorf("%s: expected %v, got %v and %v", test.name, test.allowed, allowed, err)
+		}
+
+		allowed, err = CheckResult("path", test.result, test.defined, test.pointer, true)
+		if test.err == nil {
+			if allowed != test.allowed || err != nil {
+				t.Errorf("%s, strict: expected %v, got %v and %v", test.name, test.allowed, allowed, err)
+			}
+		} else if allowed || reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+			t.Errorf("%s, strict: expected a %T, got %v and %v", test.name, test.err, allowed, err)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package utils
+
+import "context"
+
+// ContextDone reports whether ctx was cancelled or its deadline passed. That
+// happens when the caller gives up, but also when the client abandons a
+// request itself, e.g. a hedged attempt that lost. A request that failed
+// after that was most likely cut short, so its error says nothing about
+// whoever was called, be it a gateway, the discovery endpoint or a client,
+// and must not be held against them.
+func ContextDone(ctx context.Context) bool {
+	return ctx.Err() != nil
+}
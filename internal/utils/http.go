@@ -15,6 +15,19 @@ const (
 	ApplicationJson = "application/json"
 )
 
+// Implemented by response writers that want to know why a request failed.
+type errorRecorder interface {
+	RecordError(err error)
+}
+
+// RecordError passes the cause of an error response on to the response
+// writer, if it's interested, e.g. for logging.
+func RecordError(w http.ResponseWriter, err error) {
+	if recorder, ok := w.(errorRecorder); ok {
+		recorder.RecordError(err)
+	}
+}
+
 func JoinPath(base string, paths ...string) (string, error) {
 	u, err := url.Parse(base)
 	if err != nil {
@@ -33,6 +46,7 @@ func InternalServerError(w http.ResponseWriter) {
 }
 
 func AuthzError(w http.ResponseWriter, err error) {
+	RecordError(w, err)
 	http.Error(w, err.Error(), http.StatusBadRequest)
 }
 
@@ -81,11 +95,13 @@ func HasSingleQueryParameter(w http.ResponseWriter, r *http.Request, name string
 func ReadRequest(w http.ResponseWriter, r *http.Request, request interface{}) bool {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
+		RecordError(w, err)
 		http.Error(w, "could not read request body", http.StatusBadRequest)
 		return false
 	}
 
 	if err := json.Unmarshal(body, request); err != nil {
+		RecordError(w, err)
 		http.Error(w, "could not read request body", http.StatusBadRequest)
 		return false
 	}
@@ -110,6 +126,8 @@ func WriteResponse(w http.ResponseWriter, response interface{}) bool {
 }
 
 func ForwardHttpError(w http.ResponseWriter, err error) {
+	RecordError(w, err)
+
 	if httpError, ok := err.(errors.HttpError); ok && httpError.Details() != nil {
 		if bytes, err := json.Marshal(httpError.Details()); err != nil {
 			InternalServerError(w)
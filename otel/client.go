@@ -0,0 +1,132 @@
+package otel
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+)
+
+const (
+	operationCheck = "check"
+)
+
+type client struct {
+	api.Client
+
+	instrumentation *Instrumentation
+}
+
+// Client wraps a client so that every operation emits a span and records
+// its duration. Operations that don't talk to the data plane are passed
+// through as is.
+func (i *Instrumentation) Client(c api.Client) api.Client {
+	return &client{
+		Client:          c,
+		instrumentation: i,
+	}
+}
+
+func (c *client) GetData(ctx context.Context, path string, data interface{}) error {
+	ctx, end := c.start(ctx, api.OperationGetData, path)
+
+	err := c.Client.GetData(ctx, path, data)
+	end(err)
+
+	return err
+}
+
+func (c *client) PutData(ctx context.Context, path string, data interface{}) error {
+	ctx, end := c.start(ctx, api.OperationPutData, path)
+
+	err := c.Client.PutData(ctx, path, data)
+	end(err)
+
+	return err
+}
+
+func (c *client) DeleteData(ctx context.Context, path string) error {
+	ctx, end := c.start(ctx, api.OperationDeleteData, path)
+
+	err := c.Client.DeleteData(ctx, path)
+	end(err)
+
+	return err
+}
+
+func (c *client) Query(ctx context.Context, path string, input, result interface{}) error {
+	ctx, end := c.start(ctx, api.OperationQuery, path)
+
+	err := c.Client.Query(ctx, path, input, result)
+	end(err)
+
+	return err
+}
+
+func (c *client) QueryWithResponse(ctx context.Context, path string, input, result interface{}) (*api.QueryResponse, error) {
+	ctx, end := c.start(ctx, api.OperationQuery, path)
+
+	response, err := c.Client.QueryWithResponse(ctx, path, input, result)
+	if response != nil && response.DecisionId != "" {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("styra_run.decision_id", response.DecisionId))
+	}
+
+	end(err)
+
+	return response, err
+}
+
+func (c *client) Check(ctx context.Context, path string, input interface{}) (bool, error) {
+	ctx, end := c.start(ctx, operationCheck, path)
+
+	result, err := c.Client.Check(ctx, path, input)
+	end(err)
+
+	return result, err
+}
+
+func (c *client) BatchQuery(ctx context.Context, queries []api.Query, input interface{}) error {
+	ctx, end := c.start(ctx, api.OperationBatchQuery, "")
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("styra_run.batch.size", len(queries)))
+	c.instrumentation.batchSize.Record(ctx, int64(len(queries)))
+
+	err := c.Client.BatchQuery(ctx, queries, input)
+	end(err)
+
+	return err
+}
+
+func (c *client) start(ctx context.Context, operation, path string) (context.Context, func(err error)) {
+	attributes := []attribute.KeyValue{
+		attribute.String(OperationKey, operation),
+	}
+
+	ctx, span := c.instrumentation.tracer.Start(
+		ctx,
+		"styra_run."+operation,
+		trace.WithAttributes(append(attributes, attribute.String(PathKey, path))...),
+	)
+
+	start := time.Now()
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+
+		c.instrumentation.operationDuration.Record(
+			ctx,
+			time.Since(start).Seconds(),
+			metric.WithAttributes(append(attributes, attribute.Bool("error", err != nil))...),
+		)
+	}
+}
@@ -0,0 +1,17 @@
+module github.com/styrainc/styra-run-sdk-go/otel
+
+go 1.23.0
+
+require (
+	github.com/styrainc/styra-run-sdk-go v0.2.0
+	go.opentelemetry.io/otel v1.38.0
+	go.opentelemetry.io/otel/metric v1.38.0
+	go.opentelemetry.io/otel/trace v1.38.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/auto/sdk v1.2.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
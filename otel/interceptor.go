@@ -0,0 +1,48 @@
+package otel
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+)
+
+// Interceptor emits a client span for every outgoing request and injects
+// the trace context into its headers. Install it through api.Settings.
+func (i *Instrumentation) Interceptor() api.Interceptor {
+	return func(call *api.Call, next api.Invoker) (*http.Response, error) {
+		ctx, span := i.tracer.Start(
+			call.Request.Context(),
+			"styra_run.request "+call.Operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String(OperationKey, call.Operation),
+				attribute.String(PathKey, call.Path),
+				attribute.String(GatewayKey, call.Gateway),
+				attribute.String("http.request.method", call.Request.Method),
+			),
+		)
+		defer span.End()
+
+		call.Request = call.Request.WithContext(ctx)
+		i.settings.Propagator.Inject(ctx, propagation.HeaderCarrier(call.Request.Header))
+
+		response, err := next(call)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(attribute.Int(StatusKey, response.StatusCode))
+
+			if response.StatusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+			}
+		}
+
+		return response, err
+	}
+}
@@ -0,0 +1,66 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+)
+
+// Observer records request latencies, retries, failovers and discovery
+// outcomes, and adds an event per attempt to the current operation span.
+// Install it through api.Settings.
+func (i *Instrumentation) Observer() *api.Observer {
+	return &api.Observer{
+		OnAttempt: func(ctx context.Context, event *api.AttemptEvent) {
+			attributes := []attribute.KeyValue{
+				attribute.String(GatewayKey, event.Gateway),
+				attribute.Int(StatusKey, event.Code),
+			}
+
+			if event.Err != nil {
+				attributes = append(attributes, attribute.String(ErrorKey, fmt.Sprintf("%T", event.Err)))
+			}
+
+			i.requestDuration.Record(ctx, event.Duration.Seconds(), metric.WithAttributes(attributes...))
+
+			if event.Attempt > 0 && !event.Hedged {
+				i.retries.Add(ctx, 1, metric.WithAttributes(attribute.String(GatewayKey, event.Gateway)))
+			}
+
+			trace.SpanFromContext(ctx).AddEvent(
+				"attempt",
+				trace.WithAttributes(
+					append(
+						attributes,
+						attribute.Int(AttemptKey, event.Attempt),
+						attribute.Bool(HedgedKey, event.Hedged),
+					)...,
+				),
+			)
+		},
+		OnFailover: func(ctx context.Context, from, to string) {
+			i.failovers.Add(ctx, 1, metric.WithAttributes(attribute.String(GatewayKey, from)))
+
+			trace.SpanFromContext(ctx).AddEvent(
+				"failover",
+				trace.WithAttributes(
+					attribute.String("styra_run.from", from),
+					attribute.String("styra_run.to", to),
+				),
+			)
+		},
+		OnDiscovery: func(ctx context.Context, gateways []*api.Gateway, err error) {
+			outcome := "success"
+			if err != nil {
+				outcome = "failure"
+			}
+
+			i.discoveries.Add(ctx, 1, metric.WithAttributes(attribute.String(OutcomeKey, outcome)))
+		},
+	}
+}
@@ -0,0 +1,134 @@
+// Package otel instruments the SDK with OpenTelemetry tracing and metrics.
+//
+// The client wrapper emits a span per operation, the interceptor emits a
+// span per http request and propagates the trace context through W3C trace
+// context headers, and the observer records retries, failovers and discovery
+// outcomes. Proxies can be wrapped to emit a server span per request.
+package otel
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/styrainc/styra-run-sdk-go/otel"
+)
+
+// Attribute keys.
+const (
+	OperationKey = "styra_run.operation"
+	PathKey      = "styra_run.path"
+	GatewayKey   = "styra_run.gateway"
+	AttemptKey   = "styra_run.attempt"
+	HedgedKey    = "styra_run.hedged"
+	OutcomeKey   = "styra_run.outcome"
+	ProxyKey     = "styra_run.proxy"
+	StatusKey    = "http.response.status_code"
+	ErrorKey     = "error.type"
+)
+
+type Settings struct {
+	// The tracer provider. Defaults to the global one.
+	TracerProvider trace.TracerProvider
+
+	// The meter provider. Defaults to the global one.
+	MeterProvider metric.MeterProvider
+
+	// The propagator used for outgoing and incoming requests.
+	// Defaults to W3C trace context.
+	Propagator propagation.TextMapPropagator
+}
+
+// Instrumentation holds the tracer and instruments shared by
+// the client wrapper, interceptor, observer and proxies.
+type Instrumentation struct {
+	settings          *Settings
+	tracer            trace.Tracer
+	operationDuration metric.Float64Histogram
+	requestDuration   metric.Float64Histogram
+	retries           metric.Int64Counter
+	failovers         metric.Int64Counter
+	discoveries       metric.Int64Counter
+	batchSize         metric.Int64Histogram
+	proxyDuration     metric.Float64Histogram
+}
+
+func New(settings *Settings) (*Instrumentation, error) {
+	if settings.TracerProvider == nil {
+		settings.TracerProvider = otel.GetTracerProvider()
+	}
+
+	if settings.MeterProvider == nil {
+		settings.MeterProvider = otel.GetMeterProvider()
+	}
+
+	if settings.Propagator == nil {
+		settings.Propagator = propagation.TraceContext{}
+	}
+
+	meter := settings.MeterProvider.Meter(instrumentationName)
+
+	i := &Instrumentation{
+		settings: settings,
+		tracer:   settings.TracerProvider.Tracer(instrumentationName),
+	}
+
+	var err error
+
+	if i.operationDuration, err = meter.Float64Histogram(
+		"styra_run.client.operation.duration",
+		metric.WithDescription("The duration of client operations, including retries."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+
+	if i.requestDuration, err = meter.Float64Histogram(
+		"styra_run.client.request.duration",
+		metric.WithDescription("The duration of individual requests against data plane urls."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+
+	if i.retries, err = meter.Int64Counter(
+		"styra_run.client.retries",
+		metric.WithDescription("The number of retried requests."),
+	); err != nil {
+		return nil, err
+	}
+
+	if i.failovers, err = meter.Int64Counter(
+		"styra_run.client.failovers",
+		metric.WithDescription("The number of times the client moved on from a failing data plane url."),
+	); err != nil {
+		return nil, err
+	}
+
+	if i.discoveries, err = meter.Int64Counter(
+		"styra_run.client.discoveries",
+		metric.WithDescription("The number of data plane url discoveries."),
+	); err != nil {
+		return nil, err
+	}
+
+	if i.batchSize, err = meter.Int64Histogram(
+		"styra_run.client.batch.size",
+		metric.WithDescription("The number of queries per batch query."),
+	); err != nil {
+		return nil, err
+	}
+
+	if i.proxyDuration, err = meter.Float64Histogram(
+		"styra_run.proxy.duration",
+		metric.WithDescription("The duration of proxied requests."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
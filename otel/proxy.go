@@ -0,0 +1,74 @@
+package otel
+
+import (
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/styrainc/styra-run-sdk-go/types"
+)
+
+type statusWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (s *statusWriter) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Proxy wraps a proxy so that every request emits a server span, continuing
+// the trace of the incoming request, and records its duration.
+func (i *Instrumentation) Proxy(name string, proxy *types.Proxy) *types.Proxy {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		ctx := i.settings.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := i.tracer.Start(
+			ctx,
+			"styra_run.proxy "+name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String(ProxyKey, name),
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		writer := &statusWriter{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+		}
+
+		start := time.Now()
+
+		proxy.Handler(writer, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int(StatusKey, writer.status))
+
+		if writer.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(writer.status))
+		}
+
+		i.proxyDuration.Record(
+			ctx,
+			time.Since(start).Seconds(),
+			metric.WithAttributes(
+				attribute.String(ProxyKey, name),
+				attribute.Int(StatusKey, writer.status),
+			),
+		)
+	}
+
+	return &types.Proxy{
+		Method:  proxy.Method,
+		Handler: handler,
+	}
+}
@@ -0,0 +1,214 @@
+// Package prometheus exposes client and gateway health as Prometheus metrics.
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+)
+
+const (
+	defaultNamespace = "styra_run"
+	transportError   = "error"
+)
+
+type Settings struct {
+	// The metric namespace. Defaults to `styra_run`.
+	Namespace string
+
+	// The latency histogram buckets, in seconds.
+	// Defaults to prometheus.DefBuckets.
+	Buckets []float64
+
+	// Constant labels added to every metric.
+	Labels prometheus.Labels
+}
+
+// A GatewaySource reports the gateway a client currently uses. The clients
+// returned by api.New and hybrid.New implement it.
+type GatewaySource interface {
+	Gateway() string
+}
+
+// Collector is a prometheus.Collector for a single client. Requests are
+// recorded through its interceptor, retries, failovers and discovery
+// outcomes through its observer. The active gateway is read from the
+// client at collection time.
+type Collector struct {
+	requests    *prometheus.CounterVec
+	latencies   *prometheus.HistogramVec
+	retries     *prometheus.CounterVec
+	failovers   *prometheus.CounterVec
+	discoveries *prometheus.CounterVec
+	gateways    prometheus.Gauge
+	active      *prometheus.Desc
+	client      GatewaySource
+	mutex       sync.RWMutex
+}
+
+func New(settings *Settings) *Collector {
+	namespace := settings.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	buckets := settings.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+
+	return &Collector{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Name:        "requests_total",
+				Help:        "The number of requests per operation, gateway and status code.",
+				ConstLabels: settings.Labels,
+			},
+			[]string{"operation", "gateway", "code"},
+		),
+		latencies: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   namespace,
+				Name:        "request_duration_seconds",
+				Help:        "The latency of requests per operation and gateway.",
+				Buckets:     buckets,
+				ConstLabels: settings.Labels,
+			},
+			[]string{"operation", "gateway"},
+		),
+		retries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Name:        "retries_total",
+				Help:        "The number of retried requests per gateway.",
+				ConstLabels: settings.Labels,
+			},
+			[]string{"gateway"},
+		),
+		failovers: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Name:        "failovers_total",
+				Help:        "The number of times the client moved on from a failing gateway.",
+				ConstLabels: settings.Labels,
+			},
+			[]string{"from", "to"},
+		),
+		discoveries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Name:        "discoveries_total",
+				Help:        "The number of gateway discoveries per outcome.",
+				ConstLabels: settings.Labels,
+			},
+			[]string{"outcome"},
+		),
+		gateways: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Name:        "gateways",
+				Help:        "The number of gateways found by the last successful discovery.",
+				ConstLabels: settings.Labels,
+			},
+		),
+		active: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "active_gateway"),
+			"The gateway currently in use, which has the value 1.",
+			[]string{"gateway"},
+			settings.Labels,
+		),
+	}
+}
+
+// SetClient sets the client whose active gateway is reported. Since the
+// client is created with the collector's interceptor and observer, it
+// can only be set afterwards.
+func (c *Collector) SetClient(client GatewaySource) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.client = client
+}
+
+// Interceptor records the outcome and latency of every request. Install
+// it through api.Settings.
+func (c *Collector) Interceptor() api.Interceptor {
+	return func(call *api.Call, next api.Invoker) (*http.Response, error) {
+		gateway := call.Gateway
+
+		start := time.Now()
+		response, err := next(call)
+
+		code := transportError
+		if err == nil {
+			code = strconv.Itoa(response.StatusCode)
+		}
+
+		c.requests.WithLabelValues(call.Operation, gateway, code).Inc()
+		c.latencies.WithLabelValues(call.Operation, gateway).Observe(time.Since(start).Seconds())
+
+		return response, err
+	}
+}
+
+// Observer records retries, failovers and discovery outcomes. Install
+// it through api.Settings.
+func (c *Collector) Observer() *api.Observer {
+	return &api.Observer{
+		OnAttempt: func(ctx context.Context, event *api.AttemptEvent) {
+			if event.Attempt > 0 && !event.Hedged {
+				c.retries.WithLabelValues(event.Gateway).Inc()
+			}
+		},
+		OnFailover: func(ctx context.Context, from, to string) {
+			c.failovers.WithLabelValues(from, to).Inc()
+		},
+		OnDiscovery: func(ctx context.Context, gateways []*api.Gateway, err error) {
+			if err != nil {
+				c.discoveries.WithLabelValues("failure").Inc()
+				return
+			}
+
+			c.discoveries.WithLabelValues("success").Inc()
+			c.gateways.Set(float64(len(gateways)))
+		},
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.latencies.Describe(ch)
+	c.retries.Describe(ch)
+	c.failovers.Describe(ch)
+	c.discoveries.Describe(ch)
+	c.gateways.Describe(ch)
+	ch <- c.active
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.latencies.Collect(ch)
+	c.retries.Collect(ch)
+	c.failovers.Collect(ch)
+	c.discoveries.Collect(ch)
+	c.gateways.Collect(ch)
+
+	c.mutex.RLock()
+	client := c.client
+	c.mutex.RUnlock()
+
+	if client == nil {
+		return
+	}
+
+	if active := client.Gateway(); active != "" {
+		ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, 1, active)
+	}
+}
@@ -1,8 +1,10 @@
 package delete_user_binding
 
 import (
+	"log/slog"
 	"net/http"
 
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	rbac "github.com/styrainc/styra-run-sdk-go/rbac/v1"
 	"github.com/styrainc/styra-run-sdk-go/rbac/v1/proxy/shared"
@@ -23,6 +25,9 @@ type Settings struct {
 
 	// An optional callback called before user bindings are accessed.
 	OnBeforeAccess shared.OnBeforeAccess
+
+	// Optional logger for rejected requests.
+	Logger *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -43,6 +48,7 @@ func New(settings *Settings) *types.Proxy {
 
 		if settings.OnBeforeAccess != nil {
 			if code, err := settings.OnBeforeAccess(user); err != nil {
+				utils.RecordError(w, err)
 				http.Error(w, err.Error(), code)
 				return
 			}
@@ -59,6 +65,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodDelete,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "delete_user_binding", handler),
 	}
 }
@@ -1,8 +1,10 @@
 package get_roles
 
 import (
+	"log/slog"
 	"net/http"
 
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	rbac "github.com/styrainc/styra-run-sdk-go/rbac/v1"
 	"github.com/styrainc/styra-run-sdk-go/types"
@@ -18,6 +20,9 @@ type Settings struct {
 
 	// A callback to get session information.
 	GetSession types.GetSession
+
+	// Optional logger for rejected requests.
+	Logger *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -47,6 +52,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodGet,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "get_roles", handler),
 	}
 }
@@ -1,9 +1,11 @@
 package get_user_binding
 
 import (
+	"log/slog"
 	"net/http"
 
 	"github.com/styrainc/styra-run-sdk-go/internal/errors"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	rbac "github.com/styrainc/styra-run-sdk-go/rbac/v1"
 	"github.com/styrainc/styra-run-sdk-go/rbac/v1/proxy/shared"
@@ -26,6 +28,9 @@ type Settings struct {
 
 	// An optional callback called before user bindings are accessed.
 	OnBeforeAccess shared.OnBeforeAccess
+
+	// Optional logger for rejected requests.
+	Logger *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -46,6 +51,7 @@ func New(settings *Settings) *types.Proxy {
 
 		if settings.OnBeforeAccess != nil {
 			if code, err := settings.OnBeforeAccess(user); err != nil {
+				utils.RecordError(w, err)
 				http.Error(w, err.Error(), code)
 				return
 			}
@@ -70,6 +76,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodGet,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "get_user_binding", handler),
 	}
 }
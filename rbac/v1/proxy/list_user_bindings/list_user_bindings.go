@@ -1,8 +1,10 @@
 package list_user_bindings
 
 import (
+	"log/slog"
 	"net/http"
 
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	rbac "github.com/styrainc/styra-run-sdk-go/rbac/v1"
 	"github.com/styrainc/styra-run-sdk-go/rbac/v1/proxy/shared"
@@ -24,6 +26,9 @@ type Settings struct {
 	// A callback that, given an HTTP request and `page` query parameter
 	// details, emits a list of users and corresponding page information.
 	GetUsers shared.GetUsers
+
+	// Optional logger for rejected requests.
+	Logger *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -45,6 +50,7 @@ func New(settings *Settings) *types.Proxy {
 
 		users, page, err := settings.GetUsers(r, []byte(query))
 		if err != nil {
+			utils.RecordError(w, err)
 			utils.InternalServerError(w)
 			return
 		}
@@ -65,6 +71,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodGet,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "list_user_bindings", handler),
 	}
 }
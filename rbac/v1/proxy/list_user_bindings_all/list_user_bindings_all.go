@@ -1,8 +1,10 @@
 package list_user_bindings_all
 
 import (
+	"log/slog"
 	"net/http"
 
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	rbac "github.com/styrainc/styra-run-sdk-go/rbac/v1"
 	"github.com/styrainc/styra-run-sdk-go/types"
@@ -19,6 +21,9 @@ type Settings struct {
 
 	// A callback to get session information.
 	GetSession types.GetSession
+
+	// Optional logger for rejected requests.
+	Logger *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -48,6 +53,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodGet,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "list_user_bindings_all", handler),
 	}
 }
@@ -1,8 +1,10 @@
 package put_user_binding
 
 import (
+	"log/slog"
 	"net/http"
 
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	rbac "github.com/styrainc/styra-run-sdk-go/rbac/v1"
 	"github.com/styrainc/styra-run-sdk-go/rbac/v1/proxy/shared"
@@ -25,6 +27,9 @@ type Settings struct {
 
 	// An optional callback called before user bindings are accessed.
 	OnBeforeAccess shared.OnBeforeAccess
+
+	// Optional logger for rejected requests.
+	Logger *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -58,6 +63,7 @@ func New(settings *Settings) *types.Proxy {
 
 		if settings.OnBeforeAccess != nil {
 			if code, err := settings.OnBeforeAccess(user); err != nil {
+				utils.RecordError(w, err)
 				http.Error(w, err.Error(), code)
 				return
 			}
@@ -74,6 +80,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodPut,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "put_user_binding", handler),
 	}
 }
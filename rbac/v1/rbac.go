@@ -3,11 +3,13 @@ package v1
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"sort"
 
 	api "github.com/styrainc/styra-run-sdk-go/api/v1"
 	"github.com/styrainc/styra-run-sdk-go/internal/errors"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/types"
 )
 
@@ -33,6 +35,9 @@ type UserBinding struct {
 
 type Settings struct {
 	Client api.Client
+
+	// Optional logger for authorization failures.
+	Logger *slog.Logger
 }
 
 type Rbac interface {
@@ -46,11 +51,13 @@ type Rbac interface {
 
 type rbac struct {
 	settings *Settings
+	logger   *slog.Logger
 }
 
 func New(settings *Settings) Rbac {
 	return &rbac{
 		settings: settings,
+		logger:   logging.OrDiscard(settings.Logger),
 	}
 }
 
@@ -165,8 +172,13 @@ func (r *rbac) DeleteUserBinding(ctx context.Context, session *types.Session, us
 
 func (r *rbac) authz(ctx context.Context, session *types.Session) bool {
 	if result, err := r.settings.Client.Check(ctx, authzPath, session); err != nil {
+		r.logger.ErrorContext(ctx, "styra run rbac authorization check failed", "error", err)
 		return false
 	} else {
+		if !result {
+			r.logger.InfoContext(ctx, "styra run rbac authorization denied")
+		}
+
 		return result
 	}
 }
@@ -0,0 +1,62 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+	"github.com/styrainc/styra-run-sdk-go/types"
+)
+
+// A client that only answers authorization checks.
+type checkClient struct {
+	api.Client
+	allowed bool
+	err     error
+}
+
+func (c *checkClient) Check(ctx context.Context, path string, input interface{}) (bool, error) {
+	return c.allowed, c.err
+}
+
+func TestAuthzWithoutSession(t *testing.T) {
+	for _, client := range []*checkClient{
+		{allowed: false},
+		{err: errors.New("check failed")},
+	} {
+		r := New(&Settings{
+			Client: client,
+			Logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		})
+
+		if _, err := r.GetRoles(context.Background(), nil); err != authzError {
+			t.Errorf("expected an authz error, got %v", err)
+		}
+	}
+}
+
+func TestAuthzDoesNotLogSession(t *testing.T) {
+	var buffer bytes.Buffer
+
+	r := New(&Settings{
+		Client: &checkClient{allowed: false},
+		Logger: slog.New(slog.NewTextHandler(&buffer, nil)),
+	})
+
+	session := &types.Session{
+		Tenant:  "acmecorp",
+		Subject: "alice",
+	}
+
+	if _, err := r.GetRoles(context.Background(), session); err != authzError {
+		t.Fatalf("expected an authz error, got %v", err)
+	}
+
+	if output := buffer.String(); strings.Contains(output, "acmecorp") || strings.Contains(output, "alice") {
+		t.Errorf("expected the session to be left out of the logs, got %s", output)
+	}
+}
@@ -11,23 +11,43 @@ import (
 func main() {
 	token := flag.String("token", "", "token")
 	url := flag.String("url", "", "url")
+	config := flag.String("config", "", "yaml or json config file, instead of token and url")
 	port := flag.Int("port", 0, "port")
 
 	flag.Parse()
 
-	if *token == "" || *url == "" || *port == 0 {
+	if *port == 0 {
 		flag.PrintDefaults()
 		return
 	}
 
-	client := api.New(
-		&api.Settings{
+	if (*token == "") != (*url == "") {
+		log.Fatal("-token and -url must be given together")
+	}
+
+	// Flags take precedence over a config file, which takes
+	// precedence over STYRA_RUN_* environment variables.
+	var settings *api.Settings
+	var err error
+	switch {
+	case *token != "":
+		settings = &api.Settings{
 			Token:             *token,
 			Url:               *url,
 			DiscoveryStrategy: api.Simple,
 			MaxRetries:        3,
-		},
-	)
+		}
+	case *config != "":
+		settings, err = api.SettingsFromFile(*config)
+	default:
+		settings, err = api.SettingsFromEnv()
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := api.New(settings)
 
 	ws := server.NewWebServer(
 		&server.WebServerSettings{
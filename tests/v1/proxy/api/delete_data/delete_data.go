@@ -1,9 +1,11 @@
 package delete_data
 
 import (
+	"log/slog"
 	"net/http"
 
 	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	"github.com/styrainc/styra-run-sdk-go/types"
 )
@@ -13,6 +15,7 @@ type DeleteDataResponse struct{}
 type Settings struct {
 	Client  api.Client
 	GetPath types.GetVar
+	Logger  *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -34,6 +37,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodDelete,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "delete_data", handler),
 	}
 }
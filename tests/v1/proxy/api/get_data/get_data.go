@@ -1,9 +1,11 @@
 package get_data
 
 import (
+	"log/slog"
 	"net/http"
 
 	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	"github.com/styrainc/styra-run-sdk-go/types"
 )
@@ -15,6 +17,7 @@ type GetDataResponse struct {
 type Settings struct {
 	Client  api.Client
 	GetPath types.GetVar
+	Logger  *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -40,6 +43,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodGet,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "get_data", handler),
 	}
 }
@@ -1,9 +1,11 @@
 package put_data
 
 import (
+	"log/slog"
 	"net/http"
 
 	api "github.com/styrainc/styra-run-sdk-go/api/v1"
+	"github.com/styrainc/styra-run-sdk-go/internal/logging"
 	"github.com/styrainc/styra-run-sdk-go/internal/utils"
 	"github.com/styrainc/styra-run-sdk-go/types"
 )
@@ -13,6 +15,7 @@ type PutDataResponse struct{}
 type Settings struct {
 	Client  api.Client
 	GetPath types.GetVar
+	Logger  *slog.Logger
 }
 
 func New(settings *Settings) *types.Proxy {
@@ -43,6 +46,6 @@ func New(settings *Settings) *types.Proxy {
 
 	return &types.Proxy{
 		Method:  http.MethodPut,
-		Handler: handler,
+		Handler: logging.Handler(settings.Logger, "put_data", handler),
 	}
 }